@@ -0,0 +1,98 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+
+	"github.com/vmware/govmomi/simulator"
+	"golang.org/x/net/context"
+)
+
+// vcsim starts an in-process VCenter simulator with the default VPX
+// inventory: datacenter DC0, one standalone host, one cluster, the
+// LocalDS_0 datastore and VMs like DC0_H0_VM0.
+func vcsim(t *testing.T) (*url.URL, func()) {
+	model := simulator.VPX()
+	if err := model.Create(); err != nil {
+		t.Fatalf("Error creating the simulator model: %s", err)
+	}
+	server := model.Service.NewServer()
+	return server.URL, func() {
+		server.Close()
+		model.Remove()
+	}
+}
+
+// connect logs in the simulator, the connection has to be closed
+func connect(t *testing.T, u *url.URL, o ConnectOptions) *Connection {
+	c, err := Connect(u, true, o, context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	return c
+}
+
+// render writes the result with the format and returns the output
+func render(t *testing.T, format string, r Result) string {
+	var buf bytes.Buffer
+
+	f, err := NewFormatter(format)
+	if err != nil {
+		t.Fatalf("Error getting formatter %s: %s", format, err)
+	}
+	if err := f.Format(&buf, r); err != nil {
+		t.Fatalf("Error rendering %s: %s", format, err)
+	}
+	return buf.String()
+}
+
+// collect runs Search and Collect for the action
+func collect(t *testing.T, a Action, s ...string) Result {
+	if _, err := a.Search(s...); err != nil {
+		t.Fatalf("Error searching: %s", err)
+	}
+	r, err := a.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Error collecting: %s", err)
+	}
+	return r
+}
+
+func TestDatacenterNotFound(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listvms, err := NewListVMs(c, "nonexistent", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listvms.Close()
+	if _, err := listvms.Search(); KindOf(err) != ErrDatacenterNotFound {
+		t.Errorf("Expected ErrDatacenterNotFound, got: %v", err)
+	}
+}
+
+func TestConnectionError(t *testing.T) {
+	u, stop := vcsim(t)
+	stop()
+
+	if _, err := Connect(u, true, ConnectOptions{}, context.Background()); KindOf(err) != ErrConnection {
+		t.Errorf("Expected ErrConnection, got: %v", err)
+	}
+}
@@ -17,19 +17,33 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-playground/log"
 	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
 	"golang.org/x/net/context"
 )
 
-// Action Interface methods for all actions
+// Action Interface methods for all actions.
+// Search finds the references of the objects, Collect retrieves their
+// properties and Print renders them with the format defined by SetOutput.
+// Close releases the objects created in VCenter and its session.
 type Action interface {
-	Search(s ...string) int
-	Print(p ...string)
+	SetOutput(format string) error
+	Search(s ...string) (int, error)
+	Collect(ctx context.Context, p ...string) (Result, error)
+	Print(p ...string) error
+	Close() error
 }
 
 func contains(a string, list []string) bool {
@@ -41,57 +55,354 @@ func contains(a string, list []string) bool {
 	return false
 }
 
+// hasProperty returns true if the property or any of its nested
+// properties (e.g. summary.config for summary) is in the list
+func hasProperty(property string, list []string) bool {
+	for _, p := range list {
+		if p == property || strings.HasPrefix(p, property+".") {
+			return true
+		}
+	}
+	return false
+}
+
 type base struct {
-	client *govmomi.Client
-	url    *url.URL
-	dc     string
-	ctx    context.Context
+	conn      *Connection
+	client    *govmomi.Client
+	url       *url.URL
+	dc        string
+	ctx       context.Context
+	formatter Formatter
+	// objects are the views and collectors created in VCenter, which
+	// are destroyed by Close
+	objects []types.ManagedObjectReference
+}
+
+// ConnectOptions are the settings of the connection with VCenter
+type ConnectOptions struct {
+	// CABundle is a PEM file with the CAs to verify the certificate of
+	// VCenter instead of the system ones
+	CABundle string
+	// SessionDir is the directory to cache the sessions, so they are
+	// reused by the next runs. Empty disables the cache.
+	SessionDir string
+}
+
+// Connection is an authenticated session in VCenter, shared by the actions
+// created with it. The session is closed when the connection and all its
+// actions are closed, unless it is kept in the session cache.
+type Connection struct {
+	client  *govmomi.Client
+	url     *url.URL
+	options ConnectOptions
+	// cached is true if the session was restored from or saved in the
+	// session cache
+	cached bool
+	mu     sync.Mutex
+	refs   int
+}
+
+// dial connects to VCenter without logging in, with the CAs of the options
+func dial(ctx context.Context, u *url.URL, insecure bool, o ConnectOptions) (*govmomi.Client, error) {
+	sc := soap.NewClient(u, insecure)
+	if o.CABundle != "" {
+		if err := sc.SetRootCAs(o.CABundle); err != nil {
+			return nil, fmt.Errorf("Error loading CA bundle %s: %s", o.CABundle, err)
+		}
+	}
+	vc, err := vim25.NewClient(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	c := &govmomi.Client{
+		Client:         vc,
+		SessionManager: session.NewManager(vc),
+	}
+	return c, nil
+}
+
+// newClient connects and logs in VCenter like govmomi.NewClient. If the
+// session cache is enabled, the cached session is used while it is valid.
+// It returns true if the session is in the cache.
+func newClient(ctx context.Context, u *url.URL, insecure bool, o ConnectOptions) (*govmomi.Client, bool, error) {
+	c, err := dial(ctx, u, insecure, o)
+	if err != nil || u.User == nil {
+		return c, false, err
+	}
+	var cache *sessionCache
+	if o.SessionDir != "" {
+		cache = newSessionCache(o.SessionDir, u)
+		// The connections of the same user wait for the first one to
+		// save its session, so they reuse it
+		defer cache.lock()()
+		if cache.restore(ctx, c) {
+			return c, true, nil
+		}
+	}
+	if err := c.Login(ctx, u.User); err != nil {
+		return nil, false, err
+	}
+	if cache == nil {
+		return c, false, nil
+	}
+	if err := cache.save(c); err != nil {
+		// The session is still valid for this run
+		log.Errorf("Error saving the session cache: %s", err)
+		return c, false, nil
+	}
+	return c, true, nil
+}
+
+// Connect connects and logs in VCenter. The connection has to be closed
+// when the actions are created with it.
+func Connect(u *url.URL, insecure bool, o ConnectOptions, ctx context.Context) (*Connection, error) {
+	c, cached, err := newClient(ctx, u, insecure, o)
+	if err != nil {
+		return nil, newError(ErrConnection, err, "Cannot connect with %s", u.Host)
+	}
+	log.Infof("Connected to %s", u.Host)
+	return &Connection{client: c, url: u, options: o, cached: cached, refs: 1}, nil
+}
+
+// retain adds a user of the connection, it fails if it was closed
+func (c *Connection) retain() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.refs == 0 {
+		return newError(ErrConnection, nil, "Connection with %s closed", c.url.Host)
+	}
+	c.refs++
+	return nil
+}
+
+// Close releases the connection. The last user closes the session, unless
+// it is kept in the session cache.
+func (c *Connection) Close() error {
+	c.mu.Lock()
+	if c.refs == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	c.refs--
+	last := c.refs == 0
+	c.mu.Unlock()
+	if !last || c.cached || c.url.User == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	if err := c.client.Logout(ctx); err != nil {
+		return wrapError(err, "Error closing the session in %s", c.url.Host)
+	}
+	log.Debugf("Closed the session in %s", c.url.Host)
+	return nil
+}
+
+// newBase is the constructor, the action uses the connection until it is
+// closed
+func newBase(c *Connection, dc string, ctx context.Context) (*base, error) {
+	if err := c.retain(); err != nil {
+		return nil, err
+	}
+	b := base{conn: c, client: c.client, url: c.url, dc: dc, ctx: ctx, formatter: tableFormatter{}}
+	log.Infof("Using datacenter %s in %s", dc, c.url.Host)
+	return &b, nil
+}
+
+// closeTimeout is the time given to Close to release the objects
+const closeTimeout = 10 * time.Second
+
+// newCollector creates a PropertyCollector for the action, which is
+// destroyed by Close
+func (b *base) newCollector(ctx context.Context) (*property.Collector, error) {
+	pc, err := property.DefaultCollector(b.client.Client).Create(ctx)
+	if err != nil {
+		return nil, wrapError(err, "Error creating collector")
+	}
+	b.objects = append(b.objects, pc.Reference())
+	return pc, nil
+}
+
+// Close destroys the views and collectors created by the action and
+// releases its connection. It does not use the context of the action, so
+// it also works after it was cancelled.
+func (b *base) Close() error {
+	var first error
+
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	for _, ref := range b.objects {
+		var err error
+		switch ref.Type {
+		case "ContainerView":
+			_, err = methods.DestroyView(ctx, b.client.RoundTripper, &types.DestroyView{This: ref})
+		case "PropertyCollector":
+			_, err = methods.DestroyPropertyCollector(ctx, b.client.RoundTripper, &types.DestroyPropertyCollector{This: ref})
+		}
+		if err != nil && first == nil {
+			first = wrapError(err, "Error destroying %s %s", ref.Type, ref.Value)
+		}
+	}
+	log.Debugf("Destroyed %d objects in %s", len(b.objects), b.url.Host)
+	b.objects = nil
+	if b.conn != nil {
+		if err := b.conn.Close(); err != nil && first == nil {
+			first = err
+		}
+		b.conn = nil
+	}
+	return first
+}
+
+// SetOutput defines the format used by Print: table (default), json,
+// yaml or csv
+func (b *base) SetOutput(format string) error {
+	f, err := NewFormatter(format)
+	if err != nil {
+		return err
+	}
+	b.formatter = f
+	return nil
 }
 
-// newBase is the constructor
-func newBase(u *url.URL, insecure bool, dc string, ctx context.Context) *base {
-	c, err := govmomi.NewClient(ctx, u, insecure)
+// print writes the result of Collect to stdout with the formatter
+// selected by SetOutput
+func (b *base) print(r Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if err := b.formatter.Format(os.Stdout, r); err != nil {
+		return fmt.Errorf("Error rendering output: %s", err)
+	}
+	return nil
+}
+
+// createView creates a container view with the objects of the kinds
+// requested, found recursively from the container (e.g. a datacenter)
+func (b *base) createView(container types.ManagedObjectReference, kinds ...string) (types.ManagedObjectReference, error) {
+	var viewManager mo.ViewManager
+
+	// http://www.geeklee.co.uk/object-properties-containerview-pyvmomi
+	// Create the view manager
+	if err := b.client.RetrieveOne(b.ctx, *b.client.ServiceContent.ViewManager, nil, &viewManager); err != nil {
+		return types.ManagedObjectReference{}, wrapError(err, "Error creating viewManager")
+	}
+	// Create the CreateContentView request
+	req := types.CreateContainerView{
+		This:      viewManager.Reference(),
+		Container: container,
+		Type:      kinds,
+		Recursive: true,
+	}
+	res, err := methods.CreateContainerView(b.ctx, b.client.RoundTripper, &req)
 	if err != nil {
-		log.Panicf("Cannot connect with %s: %s", u, err)
+		return types.ManagedObjectReference{}, wrapError(err, "Error creating container view")
 	}
-	b := base{c, u, dc, ctx}
-	log.Infof("Connected to %s. Using datacenter %s", u, dc)
-	return &b
+	b.objects = append(b.objects, res.Returnval)
+	return res.Returnval, nil
 }
 
-func (b *base) clonesession() string {
-	var token string
+// viewReferences gets the references of the objects of the view
+func (b *base) viewReferences(view types.ManagedObjectReference, kinds ...string) ([]types.ManagedObjectReference, error) {
+	var containerView mo.ContainerView
+	var refs []types.ManagedObjectReference
 
+	log.Debugf("Getting list of %s references ...", strings.Join(kinds, ", "))
+	if err := b.client.RetrieveOne(b.ctx, view, nil, &containerView); err != nil {
+		return nil, wrapError(err, "Error retrieving references")
+	}
+	for _, mor := range containerView.View {
+		if contains(mor.Type, kinds) {
+			refs = append(refs, mor)
+		}
+	}
+	return refs, nil
+}
+
+// containerView gets the references of all the objects of the kinds
+// requested, found recursively from the container (e.g. a datacenter)
+func (b *base) containerView(container types.ManagedObjectReference, kinds ...string) ([]types.ManagedObjectReference, error) {
+	view, err := b.createView(container, kinds...)
+	if err != nil {
+		return nil, err
+	}
+	return b.viewReferences(view, kinds...)
+}
+
+// retrieveView gets the properties of all the objects of the kind in the
+// view with a single traversal done by the PropertyCollector, so only the
+// properties requested are sent by VCenter and the references of the
+// objects are not needed.
+func (b *base) retrieveView(ctx context.Context, pc *property.Collector, view types.ManagedObjectReference, kind string, ps []string, dst interface{}) error {
+	spec := types.PropertyFilterSpec{
+		ObjectSet: []types.ObjectSpec{
+			{
+				Obj:  view,
+				Skip: types.NewBool(true),
+				SelectSet: []types.BaseSelectionSpec{
+					&types.TraversalSpec{
+						Type: "ContainerView",
+						Path: "view",
+						Skip: types.NewBool(false),
+					},
+				},
+			},
+		},
+		PropSet: []types.PropertySpec{
+			{
+				Type:    kind,
+				PathSet: ps,
+			},
+		},
+	}
+	req := types.RetrieveProperties{
+		This:    pc.Reference(),
+		SpecSet: []types.PropertyFilterSpec{spec},
+	}
+	res, err := pc.RetrieveProperties(ctx, req)
+	if err != nil {
+		return wrapError(err, "Error retrieving %s properties from the view", kind)
+	}
+	if err := mo.LoadRetrievePropertiesResponse(res, dst); err != nil {
+		return wrapError(err, "Error loading %s properties", kind)
+	}
+	return nil
+}
+
+func (b *base) clonesession() (string, error) {
 	gclient := b.client
 	req := types.AcquireCloneTicket{
 		This: gclient.SessionManager.Reference(),
 	}
-	if res, err := methods.AcquireCloneTicket(b.ctx, gclient.RoundTripper, &req); err == nil {
-		token = res.Returnval
-		log.Debugf("Cloning current session from %s: %s ", b.url, token)
-	} else {
-		log.Errorf("Error cloning session tiket from %s: %s", b.url, err)
+	res, err := methods.AcquireCloneTicket(b.ctx, gclient.RoundTripper, &req)
+	if err != nil {
+		return "", wrapError(err, "Error cloning session ticket from %s", b.url.Host)
 	}
-	return token
+	log.Debugf("Cloning current session from %s: %s ", b.url.Host, res.Returnval)
+	return res.Returnval, nil
 }
 
-func (b *base) fingerprint() string {
+func (b *base) fingerprint() (string, error) {
 	var fingerpring []string
 
 	// Do a HEAD request to Vcenter to know the TLS sha1 fingerprint
 	// otherwise it could be decoded from the ticket response, but this
 	// is the right way to do it
 	log.Debugf("Getting SSL fingerprint from %s", b.client.URL().String())
-	if response, err := b.client.Head(b.client.URL().String()); err == nil {
-		rawfingerprint := fmt.Sprintf("%x", sha1.Sum(response.TLS.PeerCertificates[0].Raw))
-		log.Debugf("FingerPrint from %s: %s", b.client.URL().String(), rawfingerprint)
-		for i := 0; i < len(rawfingerprint)-1; i = i + 2 {
-			fingerpring = append(fingerpring, strings.ToUpper(rawfingerprint[i:i+2]))
-		}
-	} else {
-		log.Panicf("Error getting SSL fingerprint from %s: %s", b.client.URL().String(), err)
+	response, err := b.client.Head(b.client.URL().String())
+	if err != nil {
+		return "", newError(ErrConnection, err, "Error getting SSL fingerprint from %s", b.client.URL().Host)
+	}
+	response.Body.Close()
+	if response.TLS == nil || len(response.TLS.PeerCertificates) == 0 {
+		return "", newError(ErrConnection, nil, "No SSL certificate from %s", b.client.URL().Host)
+	}
+	rawfingerprint := fmt.Sprintf("%x", sha1.Sum(response.TLS.PeerCertificates[0].Raw))
+	log.Debugf("FingerPrint from %s: %s", b.client.URL().String(), rawfingerprint)
+	for i := 0; i < len(rawfingerprint)-1; i = i + 2 {
+		fingerpring = append(fingerpring, strings.ToUpper(rawfingerprint[i:i+2]))
 	}
-	return strings.Join(fingerpring, ":")
+	return strings.Join(fingerpring, ":"), nil
 }
 
 func (b *base) host() string {
@@ -0,0 +1,87 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestClose(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+
+	c := connect(t, u, ConnectOptions{})
+	listvms, err := NewListVMs(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	collect(t, listvms, "*")
+	// The container view and the property collector
+	if len(listvms.objects) != 2 {
+		t.Errorf("Expected 2 objects created in VCenter, got %v", listvms.objects)
+	}
+	if err := listvms.Close(); err != nil {
+		t.Fatalf("Error closing: %s", err)
+	}
+	if len(listvms.objects) != 0 {
+		t.Errorf("Objects not destroyed: %v", listvms.objects)
+	}
+	// The session is closed with the last user of the connection
+	if session, err := listvms.client.SessionManager.UserSession(context.Background()); session == nil || err != nil {
+		t.Errorf("Session closed before the connection: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Error closing the connection: %s", err)
+	}
+	if _, err := NewListVMs(c, "DC0", context.Background()); KindOf(err) != ErrConnection {
+		t.Errorf("Expected an error using a closed connection, got %v", err)
+	}
+	if session, err := listvms.client.SessionManager.UserSession(context.Background()); session != nil || err != nil {
+		t.Errorf("Session not closed: %v, %v", session, err)
+	}
+	// The cached sessions are kept
+	dir, err := ioutil.TempDir("", "wminfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c = connect(t, u, ConnectOptions{SessionDir: dir})
+	if listvms, err = NewListVMs(c, "DC0", context.Background()); err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	c.Close()
+	if err := listvms.Close(); err != nil {
+		t.Fatalf("Error closing: %s", err)
+	}
+	if session, err := listvms.client.SessionManager.UserSession(context.Background()); session == nil || err != nil {
+		t.Errorf("Cached session closed: %v", err)
+	}
+	// The sessions which could not be saved in the cache are closed
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	c = connect(t, u, ConnectOptions{SessionDir: filepath.Join(file, "sessions")})
+	if err := c.Close(); err != nil {
+		t.Fatalf("Error closing the connection: %s", err)
+	}
+	if session, err := c.client.SessionManager.UserSession(context.Background()); session != nil || err != nil {
+		t.Errorf("Session not saved in the cache kept open: %v, %v", session, err)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+// Defaults of the web console of VCenter
+const (
+	DefaultConsoleScheme   = "http"
+	DefaultConsolePort     = 7331
+	DefaultConsoleWait     = 60 * time.Second
+	DefaultConsoleTemplate = "{{.Scheme}}://{{.VCenter}}:{{.Port}}/console/?vmId={{.VMID}}&vmName={{.VMName}}&host={{.Host}}&sessionTicket={{.Ticket}}&thumbprint={{.Thumbprint}}"
+)
+
+// ConsoleOptions defines the console URLs given by show. The Template is
+// a text/template with the fields Scheme, VCenter, Port, VMID, VMName
+// (query escaped), Host, Ticket and Thumbprint. Wait is the time to keep
+// the session open after printing the URLs, so they can be opened.
+type ConsoleOptions struct {
+	Enabled  bool
+	Scheme   string
+	Port     int
+	Template string
+	Wait     time.Duration
+}
+
+// consoleData are the fields of the console URL template
+type consoleData struct {
+	Scheme     string
+	VCenter    string
+	Port       int
+	VMID       string
+	VMName     string
+	Host       string
+	Ticket     string
+	Thumbprint string
+}
+
+// DefaultConsoleOptions returns the defaults, with the console disabled
+func DefaultConsoleOptions() ConsoleOptions {
+	return ConsoleOptions{
+		Scheme:   DefaultConsoleScheme,
+		Port:     DefaultConsolePort,
+		Template: DefaultConsoleTemplate,
+		Wait:     DefaultConsoleWait,
+	}
+}
+
+func (o ConsoleOptions) template() (*template.Template, error) {
+	t, err := template.New("console").Option("missingkey=error").Parse(o.Template)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid console URL template: %s", err)
+	}
+	return t, nil
+}
+
+// Validate checks the options, the template is rendered with dummy data
+// to find the unknown fields
+func (o ConsoleOptions) Validate() error {
+	if o.Port <= 0 || o.Port > 65535 {
+		return fmt.Errorf("Invalid console port %d", o.Port)
+	}
+	if o.Wait < 0 {
+		return fmt.Errorf("Invalid console wait %s", o.Wait)
+	}
+	t, err := o.template()
+	if err != nil {
+		return err
+	}
+	if err := t.Execute(ioutil.Discard, consoleData{}); err != nil {
+		return fmt.Errorf("Invalid console URL template: %s", err)
+	}
+	return nil
+}
+
+// SetConsole sets the console options, the console URLs are only
+// generated if they are enabled
+func (showvm *ShowVM) SetConsole(o ConsoleOptions) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	showvm.consoleOptions = o
+	return nil
+}
+
+// console returns the URL to open the web console of the VM with a new
+// ticket of the current session
+func (showvm *ShowVM) console(vm *mo.VirtualMachine) (string, error) {
+	var buf bytes.Buffer
+
+	t, err := showvm.consoleOptions.template()
+	if err != nil {
+		return "", err
+	}
+	sessionTicket, err := showvm.clonesession()
+	if err != nil {
+		return "", err
+	}
+	thumbprint, err := showvm.fingerprint()
+	if err != nil {
+		return "", err
+	}
+	data := consoleData{
+		Scheme:     showvm.consoleOptions.Scheme,
+		VCenter:    showvm.url.Host,
+		Port:       showvm.consoleOptions.Port,
+		VMID:       vm.Reference().Value,
+		VMName:     url.QueryEscape(vm.Name),
+		Host:       showvm.host(),
+		Ticket:     sessionTicket,
+		Thumbprint: thumbprint,
+	}
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("Error rendering console URL: %s", err)
+	}
+	return buf.String(), nil
+}
+
+// hasConsoles returns true if any of the VMs of the result of show has a
+// console URL
+func hasConsoles(r Result) bool {
+	if list, ok := r.(VMDetailList); ok {
+		for _, d := range list {
+			if d.Console != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// waitConsole keeps the session open for the console URLs during the
+// time defined in the options, or until the context is cancelled (e.g.
+// by SIGINT)
+func (showvm *ShowVM) waitConsole(out io.Writer) {
+	wait := showvm.consoleOptions.Wait
+	if wait == 0 {
+		return
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "You have %s to open the console URL(s), then the session will be terminated.\n", wait)
+	fmt.Fprintln(out, "Press Ctrl-C to exit now.")
+	select {
+	case <-time.After(wait):
+	case <-showvm.ctx.Done():
+	}
+}
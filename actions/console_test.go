@@ -0,0 +1,45 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConsoleOptions(t *testing.T) {
+	if err := DefaultConsoleOptions().Validate(); err != nil {
+		t.Errorf("Default console options not valid: %s", err)
+	}
+	tests := map[string]func(o *ConsoleOptions){
+		"port":     func(o *ConsoleOptions) { o.Port = 0 },
+		"wait":     func(o *ConsoleOptions) { o.Wait = -time.Second },
+		"syntax":   func(o *ConsoleOptions) { o.Template = "{{.VMID" },
+		"unknown":  func(o *ConsoleOptions) { o.Template = "{{.Unknown}}" },
+		"too high": func(o *ConsoleOptions) { o.Port = 70000 },
+	}
+	for name, change := range tests {
+		o := DefaultConsoleOptions()
+		change(&o)
+		if err := o.Validate(); err == nil {
+			t.Errorf("Invalid console options %s accepted: %+v", name, o)
+		}
+	}
+	o := DefaultConsoleOptions()
+	o.Wait = 0
+	o.Template = "https://proxy/?vm={{.VMID}}&ticket={{.Ticket}}"
+	if err := o.Validate(); err != nil {
+		t.Errorf("Custom console options not valid: %s", err)
+	}
+}
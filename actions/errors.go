@@ -0,0 +1,98 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// ErrorKind classifies the errors returned by the actions, so callers
+// can react differently to each kind of failure
+type ErrorKind int
+
+// Kinds of errors
+const (
+	ErrUnknown ErrorKind = iota
+	ErrConnection
+	ErrDatacenterNotFound
+	ErrNotFound
+	ErrPermission
+	ErrTooMany
+)
+
+// Error is the error returned by the actions
+type Error struct {
+	Kind ErrorKind
+	Msg  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", e.Msg, e.Err)
+	}
+	return e.Msg
+}
+
+// KindOf returns the kind of the error, ErrUnknown if it was not returned
+// by an action
+func KindOf(err error) ErrorKind {
+	if e, ok := err.(*Error); ok {
+		return e.Kind
+	}
+	return ErrUnknown
+}
+
+func newError(kind ErrorKind, err error, format string, a ...interface{}) *Error {
+	return &Error{kind, fmt.Sprintf(format, a...), err}
+}
+
+// wrapError classifies the errors from VCenter: missing privileges,
+// expired sessions and objects not found.
+func wrapError(err error, format string, a ...interface{}) *Error {
+	kind := ErrUnknown
+	switch err.(type) {
+	case *find.NotFoundError:
+		kind = ErrNotFound
+	}
+	var fault interface{}
+	if soap.IsSoapFault(err) {
+		fault = soap.ToSoapFault(err).VimFault()
+	} else if soap.IsVimFault(err) {
+		fault = soap.ToVimFault(err)
+	}
+	switch fault.(type) {
+	case types.NoPermission, *types.NoPermission:
+		kind = ErrPermission
+	case types.NotAuthenticated, *types.NotAuthenticated:
+		kind = ErrConnection
+	}
+	return newError(kind, err, format, a...)
+}
+
+// datacenterError wraps the errors returned by the finder when looking
+// for a datacenter
+func datacenterError(err error, dc string) *Error {
+	switch err.(type) {
+	case *find.DefaultMultipleFoundError:
+		return newError(ErrDatacenterNotFound, err, "Several datacenters found, select one or use a glob like '*'")
+	case *find.NotFoundError, *find.DefaultNotFoundError, *find.MultipleFoundError:
+		return newError(ErrDatacenterNotFound, err, "Error getting datacenter '%s'", dc)
+	}
+	return wrapError(err, "Error getting datacenter '%s'", dc)
+}
@@ -0,0 +1,359 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type filterKind int
+
+const (
+	filterString filterKind = iota
+	filterNumber
+	filterBool
+)
+
+// filterFields are the fields of the VMs which can be used in a filter
+var filterFields = map[string]filterKind{
+	"name":     filterString,
+	"hostname": filterString,
+	"guest":    filterString,
+	"power":    filterString,
+	"ip":       filterString,
+	"host":     filterString,
+	"mem":      filterNumber,
+	"cpu":      filterNumber,
+	"template": filterBool,
+}
+
+// filterOperators in the order they have to be tried, the longest first
+var filterOperators = []string{"==", "!=", "!~", "<=", ">=", "=", "~", "<", ">"}
+
+// filterValue returns the value of the field of the VM
+func (v *VMSummary) filterValue(field string) string {
+	switch field {
+	case "name":
+		return v.Name
+	case "hostname":
+		return v.HostName
+	case "guest":
+		return v.GuestID
+	case "power":
+		return v.PowerState
+	case "ip":
+		return v.IPAddress
+	case "host":
+		return v.Host
+	case "mem":
+		return strconv.Itoa(int(v.MemoryMB))
+	case "cpu":
+		return strconv.Itoa(int(v.NumCPU))
+	case "template":
+		return strconv.FormatBool(v.Template)
+	}
+	return ""
+}
+
+type filterNode interface {
+	match(v *VMSummary) bool
+}
+
+type filterAnd []filterNode
+
+func (n filterAnd) match(v *VMSummary) bool {
+	for _, c := range n {
+		if !c.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+type filterOr []filterNode
+
+func (n filterOr) match(v *VMSummary) bool {
+	for _, c := range n {
+		if c.match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+type filterNot struct {
+	node filterNode
+}
+
+func (n filterNot) match(v *VMSummary) bool {
+	return !n.node.match(v)
+}
+
+type filterCondition struct {
+	field  string
+	kind   filterKind
+	op     string
+	value  string
+	number int64
+	re     *regexp.Regexp
+}
+
+func (c *filterCondition) match(v *VMSummary) bool {
+	value := v.filterValue(c.field)
+	switch c.op {
+	case "~":
+		return c.re.MatchString(value)
+	case "!~":
+		return !c.re.MatchString(value)
+	}
+	if c.kind == filterNumber {
+		n, _ := strconv.ParseInt(value, 10, 64)
+		switch c.op {
+		case "=":
+			return n == c.number
+		case "!=":
+			return n != c.number
+		case "<":
+			return n < c.number
+		case "<=":
+			return n <= c.number
+		case ">":
+			return n > c.number
+		case ">=":
+			return n >= c.number
+		}
+		return false
+	}
+	equal := strings.EqualFold(value, c.value)
+	if c.op == "!=" {
+		return !equal
+	}
+	return equal
+}
+
+// Filter is a compiled filter expression for the vms action. The
+// conditions have the form <field><operator><value> and can be combined
+// with &&, ||, ! and parentheses, e.g.:
+//
+//	power=poweredOn && guest~ubuntu && mem>=8192
+//
+// The operators are = and != (case insensitive), ~ and !~ (regular
+// expression, case insensitive) and <, <=, >, >= for the numeric fields.
+// The values with spaces or &|() have to be quoted.
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// FilterFields returns the names of the fields supported by the filters
+func FilterFields() []string {
+	fields := []string{}
+	for f := range filterFields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// ParseFilter compiles the filter expression
+func ParseFilter(expr string) (*Filter, error) {
+	p := filterParser{input: expr}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected '%s'", p.input[p.pos:])
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// Match returns true if the VM matches the filter
+func (f *Filter) Match(v *VMSummary) bool {
+	return f.root.match(v)
+}
+
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// filterParser is a recursive descent parser of the filter expressions:
+//
+//	or        = and { "||" and }
+//	and       = unary { "&&" unary }
+//	unary     = "!" unary | "(" or ")" | condition
+//	condition = field operator value
+type filterParser struct {
+	input string
+	pos   int
+}
+
+func (p *filterParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Invalid filter at position %d: %s", p.pos+1, fmt.Sprintf(format, a...))
+}
+
+func (p *filterParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// consume skips the token if it is next in the input
+func (p *filterParser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.input[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := filterOr{node}
+	for p.consume("||") {
+		if node, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	nodes := filterAnd{node}
+	for p.consume("&&") {
+		if node, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.consume("!") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node}, nil
+	}
+	if p.consume("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("missing ')'")
+		}
+		return node, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *filterParser) parseCondition() (filterNode, error) {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || p.input[p.pos] == '_') {
+		p.pos++
+	}
+	field := strings.ToLower(p.input[start:p.pos])
+	if field == "" {
+		return nil, p.errorf("missing field, use one of %s", strings.Join(FilterFields(), ", "))
+	}
+	kind, ok := filterFields[field]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown field '%s', use one of %s", field, strings.Join(FilterFields(), ", "))
+	}
+	c := filterCondition{field: field, kind: kind}
+	for _, op := range filterOperators {
+		if p.consume(op) {
+			c.op = op
+			break
+		}
+	}
+	switch c.op {
+	case "":
+		return nil, p.errorf("missing operator after '%s'", field)
+	case "==":
+		c.op = "="
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	c.value = value
+	switch {
+	case c.op == "~" || c.op == "!~":
+		if c.re, err = regexp.Compile("(?i)" + value); err != nil {
+			return nil, p.errorf("invalid regular expression '%s': %s", value, err)
+		}
+	case kind == filterNumber:
+		if c.number, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, p.errorf("'%s' needs a number, got '%s'", field, value)
+		}
+	case c.op != "=" && c.op != "!=":
+		return nil, p.errorf("operator %s not supported by '%s'", c.op, field)
+	case kind == filterBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, p.errorf("'%s' needs true or false, got '%s'", field, value)
+		}
+		c.value = strconv.FormatBool(b)
+	}
+	return &c, nil
+}
+
+func (p *filterParser) parseValue() (string, error) {
+	p.skipSpaces()
+	if p.pos < len(p.input) && (p.input[p.pos] == '"' || p.input[p.pos] == '\'') {
+		quote := p.input[p.pos]
+		end := strings.IndexByte(p.input[p.pos+1:], quote)
+		if end < 0 {
+			return "", p.errorf("missing closing quote")
+		}
+		value := p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return value, nil
+	}
+	start := p.pos
+	for p.pos < len(p.input) && !unicode.IsSpace(rune(p.input[p.pos])) && !strings.ContainsRune("&|()", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("missing value")
+	}
+	return p.input[start:p.pos], nil
+}
@@ -0,0 +1,55 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	vm := VMSummary{
+		Name:       "web-01",
+		GuestID:    "ubuntu64Guest",
+		PowerState: "poweredOn",
+		IPAddress:  "10.0.0.5",
+		Host:       "esxi-01",
+		MemoryMB:   8192,
+		NumCPU:     4,
+	}
+	for expr, match := range map[string]bool{
+		"power=poweredOn && guest~ubuntu && mem>=8192": true,
+		"power=POWEREDON":                             true,
+		"name==web-01":                                true,
+		"mem>8192 || cpu<=4":                          true,
+		"!(cpu<4) && template=false":                  true,
+		"name='web 01' || ip!~'^10\\.'":               false,
+		"guest!~ubuntu":                               false,
+		"power!=poweredOn || (host=esxi-02 && mem<1)": false,
+		"!template=false":                             false,
+	} {
+		f, err := ParseFilter(expr)
+		if err != nil {
+			t.Errorf("Error parsing %q: %s", expr, err)
+			continue
+		}
+		if f.Match(&vm) != match {
+			t.Errorf("Filter %q should return %t", expr, match)
+		}
+	}
+	for _, expr := range []string{"", "size>1", "mem>lots", "name<a", "power=", "name=a &&", "(cpu=1", "guest~(", "template=maybe", "name='a"} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("Invalid filter %q accepted", expr)
+		}
+	}
+}
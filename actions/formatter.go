@@ -0,0 +1,107 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats accepted by SetOutput
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputCSV   = "csv"
+)
+
+// Result is the data collected by an action. Each action returns its own
+// type (VMList, DatastoreList, ...), which knows how to render itself as
+// a table for humans.
+type Result interface {
+	WriteTable(w io.Writer) error
+}
+
+// Formatter renders the result of an action
+type Formatter interface {
+	Format(w io.Writer, r Result) error
+}
+
+// Tabular is implemented by the results which can be rendered as a
+// list of records, using the same columns as the table output.
+type Tabular interface {
+	Header() []string
+	Rows() [][]string
+}
+
+var formatters = map[string]Formatter{
+	OutputTable: tableFormatter{},
+	OutputJSON:  jsonFormatter{},
+	OutputYAML:  yamlFormatter{},
+	OutputCSV:   csvFormatter{},
+}
+
+// NewFormatter returns the formatter for the output format
+func NewFormatter(format string) (Formatter, error) {
+	if f, ok := formatters[format]; ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("Unknown output format: %s", format)
+}
+
+type tableFormatter struct{}
+
+func (tableFormatter) Format(w io.Writer, r Result) error {
+	return r.WriteTable(w)
+}
+
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(w io.Writer, r Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type yamlFormatter struct{}
+
+func (yamlFormatter) Format(w io.Writer, r Result) error {
+	out, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+type csvFormatter struct{}
+
+func (csvFormatter) Format(w io.Writer, r Result) error {
+	t, ok := r.(Tabular)
+	if !ok {
+		return fmt.Errorf("Output format %s is not supported by this action", OutputCSV)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Header()); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(t.Rows()); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,230 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-playground/log"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+// ClusterInfo is a ClusterComputeResource with its capacity, DRS and HA
+// configuration. CPU is in MHz and memory in bytes.
+type ClusterInfo struct {
+	Reference        string `json:"reference" yaml:"reference"`
+	Name             string `json:"name" yaml:"name"`
+	Status           string `json:"status" yaml:"status"`
+	Hosts            int32  `json:"hosts" yaml:"hosts"`
+	EffectiveHosts   int32  `json:"effective_hosts" yaml:"effective_hosts"`
+	TotalCPU         int32  `json:"total_cpu_mhz" yaml:"total_cpu_mhz"`
+	EffectiveCPU     int32  `json:"effective_cpu_mhz" yaml:"effective_cpu_mhz"`
+	TotalMemory      int64  `json:"total_memory" yaml:"total_memory"`
+	EffectiveMemory  int64  `json:"effective_memory" yaml:"effective_memory"`
+	DRSEnabled       bool   `json:"drs_enabled" yaml:"drs_enabled"`
+	DRSBehavior      string `json:"drs_behavior,omitempty" yaml:"drs_behavior,omitempty"`
+	HAEnabled        bool   `json:"ha_enabled" yaml:"ha_enabled"`
+	AdmissionControl bool   `json:"admission_control" yaml:"admission_control"`
+	AdmissionPolicy  string `json:"admission_policy,omitempty" yaml:"admission_policy,omitempty"`
+}
+
+// ClusterList is the data collected by the clusters action
+type ClusterList []ClusterInfo
+
+// Header returns the columns of the clusters table
+func (l ClusterList) Header() []string {
+	return []string{"Reference", "Name", "Status", "Hosts", "TotalCpu", "EffectiveCpu", "TotalMemory", "EffectiveMemory", "DRS", "HA", "AdmissionControl"}
+}
+
+// Rows returns one record per cluster, CPU in MHz and memory in bytes
+func (l ClusterList) Rows() [][]string {
+	rows := [][]string{}
+	for _, c := range l {
+		rows = append(rows, []string{
+			c.Reference,
+			c.Name,
+			c.Status,
+			strconv.Itoa(int(c.Hosts)),
+			strconv.Itoa(int(c.TotalCPU)),
+			strconv.Itoa(int(c.EffectiveCPU)),
+			strconv.FormatInt(c.TotalMemory, 10),
+			strconv.FormatInt(c.EffectiveMemory, 10),
+			c.drs(),
+			strconv.FormatBool(c.HAEnabled),
+			c.admission(),
+		})
+	}
+	return rows
+}
+
+func (c *ClusterInfo) drs() string {
+	if !c.DRSEnabled {
+		return "disabled"
+	}
+	return c.DRSBehavior
+}
+
+// admission describes the HA admission control, which does not apply
+// if HA is disabled
+func (c *ClusterInfo) admission() string {
+	if !c.HAEnabled {
+		return "-"
+	}
+	if !c.AdmissionControl {
+		return "disabled"
+	}
+	return c.AdmissionPolicy
+}
+
+// WriteTable renders the clusters as a table
+func (l ClusterList) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Reference\tName\tStatus\tHosts\tTotalCpu\tEffectiveCpu\tTotalMemory\tEffectiveMemory\tDRS\tHA\tAdmissionControl\n")
+	fmt.Fprintf(tw, "---------\t----\t------\t-----\t--------\t------------\t-----------\t---------------\t---\t--\t----------------\n")
+	for _, c := range l {
+		fmt.Fprintf(tw, "%s\t", c.Reference)
+		fmt.Fprintf(tw, "%s\t", c.Name)
+		fmt.Fprintf(tw, "%s\t", c.Status)
+		fmt.Fprintf(tw, "%d/%d\t", c.EffectiveHosts, c.Hosts)
+		fmt.Fprintf(tw, "%d MHz\t", c.TotalCPU)
+		fmt.Fprintf(tw, "%d MHz\t", c.EffectiveCPU)
+		fmt.Fprintf(tw, "%s\t", units.ByteSize(c.TotalMemory))
+		fmt.Fprintf(tw, "%s\t", units.ByteSize(c.EffectiveMemory))
+		fmt.Fprintf(tw, "%s\t", c.drs())
+		fmt.Fprintf(tw, "%t\t", c.HAEnabled)
+		fmt.Fprintf(tw, "%s\t", c.admission())
+		fmt.Fprintf(tw, "\n")
+	}
+	fmt.Fprintf(tw, "\n")
+	return tw.Flush()
+}
+
+// ListClusters represents a class to list the clusters of the datacenter
+type ListClusters struct {
+	*base
+	refs []types.ManagedObjectReference
+}
+
+// NewListClusters is the constructor
+func NewListClusters(c *Connection, dc string, ctx context.Context) (*ListClusters, error) {
+	listclusters := ListClusters{}
+	b, err := newBase(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	listclusters.base = b
+	log.Debug("ListClusters constructor")
+	return &listclusters, nil
+}
+
+// Search gets the ClusterComputeResource references from VCenter, from
+// all the folders of the datacenter.
+// It will return the number of references found.
+func (listclusters *ListClusters) Search(s ...string) (int, error) {
+	log.Debugf("Gathering Cluster references with filter: %s", strings.Join(s, ", "))
+	finder := find.NewFinder(listclusters.client.Client, true)
+	dc, err := finder.DatacenterOrDefault(listclusters.ctx, listclusters.dc)
+	if err != nil {
+		return 0, datacenterError(err, listclusters.dc)
+	}
+	refs, err := listclusters.containerView(dc.Reference(), "ClusterComputeResource")
+	if err != nil {
+		return 0, err
+	}
+	listclusters.refs = append(listclusters.refs, refs...)
+	return len(refs), nil
+}
+
+// admissionPolicy describes the HA admission control policy
+func admissionPolicy(policy types.BaseClusterDasAdmissionControlPolicy) string {
+	switch p := policy.(type) {
+	case *types.ClusterFailoverLevelAdmissionControlPolicy:
+		return fmt.Sprintf("%d host failures", p.FailoverLevel)
+	case *types.ClusterFailoverResourcesAdmissionControlPolicy:
+		return fmt.Sprintf("%d%% cpu, %d%% memory", p.CpuFailoverResourcesPercent, p.MemoryFailoverResourcesPercent)
+	case *types.ClusterFailoverHostAdmissionControlPolicy:
+		return fmt.Sprintf("%d failover hosts", len(p.FailoverHosts))
+	}
+	return "enabled"
+}
+
+// Collect retrieves the capacity and configuration of the clusters found
+// by Search. It returns a ClusterList.
+func (listclusters *ListClusters) Collect(ctx context.Context, p ...string) (Result, error) {
+	var clusters []mo.ClusterComputeResource
+
+	if len(p) == 0 {
+		p = []string{"name", "summary", "configurationEx"}
+	}
+	if len(listclusters.refs) == 0 {
+		return ClusterList{}, nil
+	}
+	pc, err := listclusters.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Collecting information ...")
+	if err := pc.Retrieve(ctx, listclusters.refs, p, &clusters); err != nil {
+		return nil, wrapError(err, "Error retrieving cluster information from references")
+	}
+	list := ClusterList{}
+	for _, cluster := range clusters {
+		c := ClusterInfo{
+			Reference: cluster.Reference().Value,
+			Name:      cluster.Name,
+		}
+		if cluster.Summary != nil {
+			s := cluster.Summary.GetComputeResourceSummary()
+			c.Status = string(s.OverallStatus)
+			c.Hosts = s.NumHosts
+			c.EffectiveHosts = s.NumEffectiveHosts
+			c.TotalCPU = s.TotalCpu
+			c.EffectiveCPU = s.EffectiveCpu
+			c.TotalMemory = s.TotalMemory
+			// The effective memory is given in MB
+			c.EffectiveMemory = s.EffectiveMemory * 1024 * 1024
+		}
+		if config, ok := cluster.ConfigurationEx.(*types.ClusterConfigInfoEx); ok {
+			if config.DrsConfig.Enabled != nil {
+				c.DRSEnabled = *config.DrsConfig.Enabled
+			}
+			c.DRSBehavior = string(config.DrsConfig.DefaultVmBehavior)
+			if config.DasConfig.Enabled != nil {
+				c.HAEnabled = *config.DasConfig.Enabled
+			}
+			if c.HAEnabled && config.DasConfig.AdmissionControlEnabled != nil {
+				c.AdmissionControl = *config.DasConfig.AdmissionControlEnabled
+			}
+			if c.AdmissionControl {
+				c.AdmissionPolicy = admissionPolicy(config.DasConfig.AdmissionControlPolicy)
+			}
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// Print dumps a table with the results
+func (listclusters *ListClusters) Print(p ...string) error {
+	return listclusters.print(listclusters.Collect(listclusters.ctx, p...))
+}
@@ -0,0 +1,45 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestListClusters(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listclusters, err := NewListClusters(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listclusters.Close()
+	list := collect(t, listclusters).(ClusterList)
+	if len(list) != 1 {
+		t.Fatalf("Expected one cluster, got %v", list)
+	}
+	cluster := list[0]
+	if cluster.Name != "DC0_C0" || cluster.Hosts == 0 || cluster.TotalMemory == 0 {
+		t.Errorf("Unexpected cluster: %+v", cluster)
+	}
+	if out := render(t, OutputCSV, list); !strings.Contains(out, "DC0_C0") {
+		t.Errorf("Cluster missing in CSV output:\n%s", out)
+	}
+}
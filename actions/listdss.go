@@ -15,20 +15,57 @@ package actions
 
 import (
 	"fmt"
-	"net/url"
-	"os"
+	"io"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/go-playground/log"
 	"github.com/vmware/govmomi/find"
-	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/units"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	"golang.org/x/net/context"
 )
 
+// DatastoreInfo is a DataStore or a DataStore Cluster (Kind StoragePod).
+// Capacity and FreeSpace are in bytes.
+type DatastoreInfo struct {
+	Reference string `json:"reference" yaml:"reference"`
+	Name      string `json:"name" yaml:"name"`
+	Kind      string `json:"kind" yaml:"kind"`
+	Type      string `json:"type,omitempty" yaml:"type,omitempty"`
+	Capacity  int64  `json:"capacity" yaml:"capacity"`
+	FreeSpace int64  `json:"free_space" yaml:"free_space"`
+}
+
+// DatastoreList is the document of the ds action
+type DatastoreList []DatastoreInfo
+
+// Header returns the columns of the ds table
+func (l DatastoreList) Header() []string {
+	return []string{"Reference", "Name", "Type", "Capacity", "FreeSpace"}
+}
+
+// Rows returns one record per DataStore, sizes in bytes
+func (l DatastoreList) Rows() [][]string {
+	rows := [][]string{}
+	for _, ds := range l {
+		t := ds.Type
+		if t == "" {
+			t = "-"
+		}
+		rows = append(rows, []string{
+			ds.Reference,
+			ds.Name,
+			t,
+			strconv.FormatInt(ds.Capacity, 10),
+			strconv.FormatInt(ds.FreeSpace, 10),
+		})
+	}
+	return rows
+}
+
 // ListDSs is class to list DataStores
 type ListDSs struct {
 	*base
@@ -37,32 +74,38 @@ type ListDSs struct {
 }
 
 // NewListDSs is the constructor
-func NewListDSs(u *url.URL, insecure bool, dc string, ctx context.Context) *ListDSs {
+func NewListDSs(c *Connection, dc string, ctx context.Context) (*ListDSs, error) {
 	listdss := ListDSs{}
-	listdss.base = newBase(u, insecure, dc, ctx)
+	b, err := newBase(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	listdss.base = b
 	log.Debug("ListDSs constructor")
-	return &listdss
+	return &listdss, nil
 }
 
 // Search gets DataStore and DataStoreCluster references from VCenter.
 // It accepts one parameter to filter the search.
 // It will return the number of references found.
-func (listdss *ListDSs) Search(s ...string) int {
+func (listdss *ListDSs) Search(s ...string) (int, error) {
 
 	if len(s) == 0 {
 		s = []string{"*"}
 	}
 	log.Debugf("Gathering Datastore references with filter: %s", strings.Join(s, ", "))
 	finder := find.NewFinder(listdss.client.Client, true)
-	if dc, err := finder.DatacenterOrDefault(listdss.ctx, listdss.dc); err != nil {
-		log.Panicf("Error getting datacenter: %s", err)
-	} else {
-		finder.SetDatacenter(dc)
+	dc, err := finder.DatacenterOrDefault(listdss.ctx, listdss.dc)
+	if err != nil {
+		return 0, datacenterError(err, listdss.dc)
 	}
+	finder.SetDatacenter(dc)
 	// Find DataStores in datacenter
 	counter := 0
 	if dss, err := finder.DatastoreList(listdss.ctx, s[0]); err != nil {
-		log.Panicf("Error retrieving datastore list: %s", err)
+		if _, ok := err.(*find.NotFoundError); !ok {
+			return 0, wrapError(err, "Error retrieving datastore list")
+		}
 	} else {
 		log.Debug("Getting list of datastore references")
 		// Convert DSs into list of references
@@ -72,7 +115,9 @@ func (listdss *ListDSs) Search(s ...string) int {
 		}
 	}
 	if dscs, err := finder.DatastoreClusterList(listdss.ctx, s[0]); err != nil {
-		log.Panicf("Error retrieving datastore cluster list: %s", err)
+		if _, ok := err.(*find.NotFoundError); !ok {
+			return 0, wrapError(err, "Error retrieving datastore cluster list")
+		}
 	} else {
 		log.Debug("Getting list of datastore cluster references")
 		for _, ds := range dscs {
@@ -80,56 +125,85 @@ func (listdss *ListDSs) Search(s ...string) int {
 			counter++
 		}
 	}
-	return counter
+	return counter, nil
 }
 
-// Print dumps a table with the results
-func (listdss *ListDSs) Print(p ...string) {
+// WriteTable renders the DataStores as a table
+func (l DatastoreList) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Reference\tName\tType\tCapacity\tFreeSpace\n")
+	fmt.Fprintf(tw, "---------\t----\t----\t--------\t---------\n")
+	for _, ds := range l {
+		fmt.Fprintf(tw, "%s\t", ds.Reference)
+		fmt.Fprintf(tw, "%s\t", ds.Name)
+		if ds.Type == "" {
+			fmt.Fprintf(tw, "%s\t", "-")
+		} else {
+			fmt.Fprintf(tw, "%s\t", ds.Type)
+		}
+		fmt.Fprintf(tw, "%s\t", units.ByteSize(ds.Capacity))
+		fmt.Fprintf(tw, "%s\t", units.ByteSize(ds.FreeSpace))
+		fmt.Fprintf(tw, "\n")
+	}
+	fmt.Fprintf(tw, "\n")
+	return tw.Flush()
+}
+
+// Collect retrieves the properties of the DataStores and DataStore Clusters
+// found by Search. It returns a DatastoreList.
+func (listdss *ListDSs) Collect(ctx context.Context, p ...string) (Result, error) {
 	var dsts []mo.Datastore
 	var dstsc []mo.StoragePod
 
 	if len(p) == 0 {
 		p = []string{"summary"}
 	}
-	if pc, err := property.DefaultCollector(listdss.client.Client).Create(listdss.ctx); err == nil {
-		log.Debug("Printing information ...")
-		tw := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
-		fmt.Fprintf(tw, "\n")
-		fmt.Fprintf(tw, "Reference\tName\tType\tCapacity\tFreeSpace\n")
-		fmt.Fprintf(tw, "---------\t----\t----\t--------\t---------\n")
-		if err := pc.Retrieve(listdss.ctx, listdss.refs, p, &dsts); err != nil {
-			log.Errorf("Error retrieving datastore information from references: %s", err)
-		} else {
-			for _, dst := range dsts {
-				fmt.Fprintf(tw, "%s\t", dst.Reference())
-				fmt.Fprintf(tw, "%s\t", dst.Name)
-				if contains("summary", p) {
-					//fmt.Fprintf(tw, "%s\t", dst.Summary.Name)
-					fmt.Fprintf(tw, "%s\t", dst.Summary.Type)
-					fmt.Fprintf(tw, "%s\t", units.ByteSize(dst.Summary.Capacity))
-					fmt.Fprintf(tw, "%s\t", units.ByteSize(dst.Summary.FreeSpace))
-				}
-				fmt.Fprintf(tw, "\n")
-			}
+	pc, err := listdss.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Collecting information ...")
+	list := DatastoreList{}
+	if len(listdss.refs) > 0 {
+		if err := pc.Retrieve(ctx, listdss.refs, p, &dsts); err != nil {
+			return nil, wrapError(err, "Error retrieving datastore information from references")
 		}
-		if err := pc.Retrieve(listdss.ctx, listdss.clusterRefs, p, &dstsc); err != nil {
-			log.Errorf("Error retrieving datastore cluster information from references: %s", err)
-		} else {
-			for _, dst := range dstsc {
-				fmt.Fprintf(tw, "%s\t", dst.Reference())
-				fmt.Fprintf(tw, "%s\t", dst.Name)
-				if contains("summary", p) {
-					//fmt.Fprintf(tw, "%s\t", dst.Summary.Name)
-					fmt.Fprintf(tw, "%s\t", "-")
-					fmt.Fprintf(tw, "%s\t", units.ByteSize(dst.Summary.Capacity))
-					fmt.Fprintf(tw, "%s\t", units.ByteSize(dst.Summary.FreeSpace))
-				}
-				fmt.Fprintf(tw, "\n")
-			}
+	}
+	for _, dst := range dsts {
+		ds := DatastoreInfo{
+			Reference: dst.Reference().String(),
+			Name:      dst.Name,
+			Kind:      "Datastore",
+		}
+		if contains("summary", p) {
+			ds.Type = dst.Summary.Type
+			ds.Capacity = dst.Summary.Capacity
+			ds.FreeSpace = dst.Summary.FreeSpace
+		}
+		list = append(list, ds)
+	}
+	if len(listdss.clusterRefs) > 0 {
+		if err := pc.Retrieve(ctx, listdss.clusterRefs, p, &dstsc); err != nil {
+			return nil, wrapError(err, "Error retrieving datastore cluster information from references")
 		}
-		fmt.Fprintf(tw, "\n")
-		tw.Flush()
-	} else {
-		log.Errorf("Error creating collector: %s", err)
 	}
+	for _, dst := range dstsc {
+		ds := DatastoreInfo{
+			Reference: dst.Reference().String(),
+			Name:      dst.Name,
+			Kind:      "StoragePod",
+		}
+		if contains("summary", p) && dst.Summary != nil {
+			ds.Capacity = dst.Summary.Capacity
+			ds.FreeSpace = dst.Summary.FreeSpace
+		}
+		list = append(list, ds)
+	}
+	return list, nil
+}
+
+// Print dumps a table with the results
+func (listdss *ListDSs) Print(p ...string) error {
+	return listdss.print(listdss.Collect(listdss.ctx, p...))
 }
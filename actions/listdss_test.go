@@ -0,0 +1,49 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestListDSs(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listdss, err := NewListDSs(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listdss.Close()
+	list := collect(t, listdss).(DatastoreList)
+	if len(list) == 0 {
+		t.Fatal("No datastores found")
+	}
+	ds := list[0]
+	if ds.Name != "LocalDS_0" || ds.Kind != "Datastore" || ds.Capacity == 0 {
+		t.Errorf("Unexpected datastore: %+v", ds)
+	}
+	if out := render(t, OutputTable, list); !strings.Contains(out, "LocalDS_0") {
+		t.Errorf("Datastore missing in table output:\n%s", out)
+	}
+	out := render(t, OutputCSV, list)
+	if !strings.HasPrefix(out, "Reference,Name,Type,Capacity,FreeSpace\n") {
+		t.Errorf("Unexpected CSV output:\n%s", out)
+	}
+}
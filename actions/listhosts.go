@@ -0,0 +1,239 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-playground/log"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+// HostInfo is an ESXi host. MemorySize is in bytes, CPUUsage in MHz and
+// MemoryUsage in MB, as reported by the quick stats.
+type HostInfo struct {
+	Reference       string `json:"reference" yaml:"reference"`
+	Name            string `json:"name" yaml:"name"`
+	Cluster         string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	ConnectionState string `json:"connection_state" yaml:"connection_state"`
+	MaintenanceMode bool   `json:"maintenance_mode" yaml:"maintenance_mode"`
+	Status          string `json:"status" yaml:"status"`
+	CPUModel        string `json:"cpu_model" yaml:"cpu_model"`
+	CPUCores        int16  `json:"cpu_cores" yaml:"cpu_cores"`
+	CPUMhz          int32  `json:"cpu_mhz" yaml:"cpu_mhz"`
+	MemorySize      int64  `json:"memory_size" yaml:"memory_size"`
+	Version         string `json:"version" yaml:"version"`
+	Build           string `json:"build" yaml:"build"`
+	VMs             int    `json:"vms" yaml:"vms"`
+	CPUUsage        int32  `json:"cpu_usage_mhz" yaml:"cpu_usage_mhz"`
+	MemoryUsage     int32  `json:"memory_usage_mb" yaml:"memory_usage_mb"`
+}
+
+// CPUUsagePercent returns the CPU usage of the host over its capacity
+func (h *HostInfo) CPUUsagePercent() int {
+	capacity := int64(h.CPUMhz) * int64(h.CPUCores)
+	if capacity == 0 {
+		return 0
+	}
+	return int(int64(h.CPUUsage) * 100 / capacity)
+}
+
+// MemoryUsagePercent returns the memory usage of the host over its capacity
+func (h *HostInfo) MemoryUsagePercent() int {
+	capacity := h.MemorySize / (1024 * 1024)
+	if capacity == 0 {
+		return 0
+	}
+	return int(int64(h.MemoryUsage) * 100 / capacity)
+}
+
+// HostList is the data collected by the hosts action
+type HostList []HostInfo
+
+// Header returns the columns of the hosts table
+func (l HostList) Header() []string {
+	return []string{"Reference", "Name", "Cluster", "State", "Maintenance", "CPU", "Cores", "Memory", "Version", "Build", "VMs", "CpuUsage", "MemUsage"}
+}
+
+// Rows returns one record per host, memory in bytes and usage in percent
+func (l HostList) Rows() [][]string {
+	rows := [][]string{}
+	for i := range l {
+		h := &l[i]
+		rows = append(rows, []string{
+			h.Reference,
+			h.Name,
+			h.Cluster,
+			h.ConnectionState,
+			strconv.FormatBool(h.MaintenanceMode),
+			h.CPUModel,
+			strconv.Itoa(int(h.CPUCores)),
+			strconv.FormatInt(h.MemorySize, 10),
+			h.Version,
+			h.Build,
+			strconv.Itoa(h.VMs),
+			strconv.Itoa(h.CPUUsagePercent()),
+			strconv.Itoa(h.MemoryUsagePercent()),
+		})
+	}
+	return rows
+}
+
+// WriteTable renders the hosts as a table
+func (l HostList) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Reference\tName\tCluster\tState\tMaintenance\tCPU\tCores\tMemory\tVersion\tBuild\tVMs\tCpuUsage\tMemUsage\n")
+	fmt.Fprintf(tw, "---------\t----\t-------\t-----\t-----------\t---\t-----\t------\t-------\t-----\t---\t--------\t--------\n")
+	for i := range l {
+		h := &l[i]
+		cluster := h.Cluster
+		if cluster == "" {
+			cluster = "-"
+		}
+		fmt.Fprintf(tw, "%s\t", h.Reference)
+		fmt.Fprintf(tw, "%s\t", h.Name)
+		fmt.Fprintf(tw, "%s\t", cluster)
+		fmt.Fprintf(tw, "%s\t", h.ConnectionState)
+		fmt.Fprintf(tw, "%t\t", h.MaintenanceMode)
+		fmt.Fprintf(tw, "%s\t", h.CPUModel)
+		fmt.Fprintf(tw, "%d\t", h.CPUCores)
+		fmt.Fprintf(tw, "%s\t", units.ByteSize(h.MemorySize))
+		fmt.Fprintf(tw, "%s\t", h.Version)
+		fmt.Fprintf(tw, "%s\t", h.Build)
+		fmt.Fprintf(tw, "%d\t", h.VMs)
+		fmt.Fprintf(tw, "%d%%\t", h.CPUUsagePercent())
+		fmt.Fprintf(tw, "%d%%\t", h.MemoryUsagePercent())
+		fmt.Fprintf(tw, "\n")
+	}
+	fmt.Fprintf(tw, "\n")
+	return tw.Flush()
+}
+
+// ListHosts represents a class to list the ESXi hosts of the datacenter
+type ListHosts struct {
+	*base
+	refs []types.ManagedObjectReference
+}
+
+// NewListHosts is the constructor
+func NewListHosts(c *Connection, dc string, ctx context.Context) (*ListHosts, error) {
+	listhosts := ListHosts{}
+	b, err := newBase(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	listhosts.base = b
+	log.Debug("ListHosts constructor")
+	return &listhosts, nil
+}
+
+// Search gets the HostSystem references from VCenter, from all the
+// standalone hosts and clusters of the datacenter.
+// It will return the number of references found.
+func (listhosts *ListHosts) Search(s ...string) (int, error) {
+	log.Debugf("Gathering HostSystem references with filter: %s", strings.Join(s, ", "))
+	finder := find.NewFinder(listhosts.client.Client, true)
+	dc, err := finder.DatacenterOrDefault(listhosts.ctx, listhosts.dc)
+	if err != nil {
+		return 0, datacenterError(err, listhosts.dc)
+	}
+	refs, err := listhosts.containerView(dc.Reference(), "HostSystem")
+	if err != nil {
+		return 0, err
+	}
+	listhosts.refs = append(listhosts.refs, refs...)
+	return len(refs), nil
+}
+
+// Collect retrieves the hardware, runtime and quick stats of the hosts
+// found by Search. It returns a HostList.
+func (listhosts *ListHosts) Collect(ctx context.Context, p ...string) (Result, error) {
+	var hosts []mo.HostSystem
+	var parents []mo.ManagedEntity
+
+	if len(p) == 0 {
+		p = []string{"name", "summary", "parent", "vm"}
+	}
+	if len(listhosts.refs) == 0 {
+		return HostList{}, nil
+	}
+	pc, err := listhosts.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Collecting information ...")
+	if err := pc.Retrieve(ctx, listhosts.refs, p, &hosts); err != nil {
+		return nil, wrapError(err, "Error retrieving host information from references")
+	}
+	// Standalone hosts have a ComputeResource as parent, skip them
+	var clusterRefs []types.ManagedObjectReference
+	for _, host := range hosts {
+		if host.Parent != nil && host.Parent.Type == "ClusterComputeResource" {
+			clusterRefs = append(clusterRefs, *host.Parent)
+		}
+	}
+	clusters := make(map[types.ManagedObjectReference]string)
+	if len(clusterRefs) > 0 {
+		if err := pc.Retrieve(ctx, clusterRefs, []string{"name"}, &parents); err != nil {
+			return nil, wrapError(err, "Error retrieving cluster names")
+		}
+		for _, c := range parents {
+			clusters[c.Reference()] = c.Name
+		}
+	}
+	list := HostList{}
+	for _, host := range hosts {
+		h := HostInfo{
+			Reference:   host.Reference().Value,
+			Name:        host.Name,
+			Status:      string(host.Summary.OverallStatus),
+			VMs:         len(host.Vm),
+			CPUUsage:    host.Summary.QuickStats.OverallCpuUsage,
+			MemoryUsage: host.Summary.QuickStats.OverallMemoryUsage,
+		}
+		if host.Parent != nil {
+			h.Cluster = clusters[*host.Parent]
+		}
+		if hw := host.Summary.Hardware; hw != nil {
+			h.CPUModel = hw.CpuModel
+			h.CPUCores = hw.NumCpuCores
+			h.CPUMhz = hw.CpuMhz
+			h.MemorySize = hw.MemorySize
+		}
+		if rt := host.Summary.Runtime; rt != nil {
+			h.ConnectionState = string(rt.ConnectionState)
+			h.MaintenanceMode = rt.InMaintenanceMode
+		}
+		if product := host.Summary.Config.Product; product != nil {
+			h.Version = product.Version
+			h.Build = product.Build
+		}
+		list = append(list, h)
+	}
+	return list, nil
+}
+
+// Print dumps a table with the results
+func (listhosts *ListHosts) Print(p ...string) error {
+	return listhosts.print(listhosts.Collect(listhosts.ctx, p...))
+}
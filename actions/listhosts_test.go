@@ -0,0 +1,50 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestListHosts(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listhosts, err := NewListHosts(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listhosts.Close()
+	list := collect(t, listhosts).(HostList)
+	clustered := 0
+	for _, h := range list {
+		if h.ConnectionState != "connected" || h.MemorySize == 0 || h.CPUCores == 0 {
+			t.Errorf("Unexpected host: %+v", h)
+		}
+		if h.Cluster == "DC0_C0" {
+			clustered++
+		}
+	}
+	if clustered == 0 || clustered == len(list) {
+		t.Errorf("Expected standalone and clustered hosts: %v", list)
+	}
+	if out := render(t, OutputTable, list); !strings.Contains(out, "DC0_H0") {
+		t.Errorf("Host missing in table output:\n%s", out)
+	}
+}
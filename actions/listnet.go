@@ -15,19 +15,50 @@ package actions
 
 import (
 	"fmt"
-	"net/url"
-	"os"
+	"io"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/go-playground/log"
 	"github.com/vmware/govmomi/find"
-	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	"golang.org/x/net/context"
 )
 
+// NetworkInfo is a network resource: a Network, a DistributedVirtualPortgroup
+// or a VmwareDistributedVirtualSwitch. Accessible is not defined for switches,
+// which list their port groups instead.
+type NetworkInfo struct {
+	Reference  string   `json:"reference" yaml:"reference"`
+	Name       string   `json:"name" yaml:"name"`
+	Kind       string   `json:"kind" yaml:"kind"`
+	Accessible *bool    `json:"accessible,omitempty" yaml:"accessible,omitempty"`
+	Portgroups []string `json:"portgroups,omitempty" yaml:"portgroups,omitempty"`
+}
+
+// NetworkList is the document of the net action
+type NetworkList []NetworkInfo
+
+// Header returns the columns of the net table
+func (l NetworkList) Header() []string {
+	return []string{"Reference", "Name", "Accessible"}
+}
+
+// Rows returns one record per network resource
+func (l NetworkList) Rows() [][]string {
+	rows := [][]string{}
+	for _, n := range l {
+		accessible := "-"
+		if n.Accessible != nil {
+			accessible = strconv.FormatBool(*n.Accessible)
+		}
+		rows = append(rows, []string{n.Reference, n.Name, accessible})
+	}
+	return rows
+}
+
 // ListNets represents a class to list network resources
 type ListNets struct {
 	*base
@@ -37,32 +68,38 @@ type ListNets struct {
 }
 
 // NewListNets is the constructor
-func NewListNets(u *url.URL, insecure bool, dc string, ctx context.Context) *ListNets {
+func NewListNets(c *Connection, dc string, ctx context.Context) (*ListNets, error) {
 	listnets := ListNets{}
-	listnets.base = newBase(u, insecure, dc, ctx)
+	b, err := newBase(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	listnets.base = b
 	log.Debug("ListNets constructor")
-	return &listnets
+	return &listnets, nil
 }
 
 // Search gets network resources references from VCenter.
 // It accepts one parameter to filter the search.
 // It will return the number of references found.
-func (listnets *ListNets) Search(s ...string) int {
+func (listnets *ListNets) Search(s ...string) (int, error) {
 
 	if len(s) == 0 {
 		s = []string{"*"}
 	}
 	log.Debugf("Gathering Network resources references with filter: %s", strings.Join(s, ", "))
 	finder := find.NewFinder(listnets.client.Client, true)
-	if dc, err := finder.DatacenterOrDefault(listnets.ctx, listnets.dc); err != nil {
-		log.Panicf("Error getting datacenter: %s", err)
-	} else {
-		finder.SetDatacenter(dc)
+	dc, err := finder.DatacenterOrDefault(listnets.ctx, listnets.dc)
+	if err != nil {
+		return 0, datacenterError(err, listnets.dc)
 	}
+	finder.SetDatacenter(dc)
 	// Find DataStores in datacenter
 	counter := 0
 	if nets, err := finder.NetworkList(listnets.ctx, s[0]); err != nil {
-		log.Panicf("Error retrieving network list: %s", err)
+		if _, ok := err.(*find.NotFoundError); !ok {
+			return 0, wrapError(err, "Error retrieving network list")
+		}
 	} else {
 		// Convert Nets into list of references
 		log.Debug("Getting list of network references")
@@ -80,11 +117,44 @@ func (listnets *ListNets) Search(s ...string) int {
 			}
 		}
 	}
-	return counter
+	return counter, nil
 }
 
-// Print dumps a table with the results
-func (listnets *ListNets) Print(p ...string) {
+// WriteTable renders the network resources as a table, with the
+// distributed switches and their port groups at the end
+func (l NetworkList) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Reference\tName\tAccessible\n")
+	fmt.Fprintf(tw, "---------\t----\t----------\n")
+	switches := false
+	for _, net := range l {
+		if net.Accessible == nil {
+			if !switches {
+				fmt.Fprintf(tw, "\n")
+				switches = true
+			}
+			fmt.Fprintf(tw, "%s\t", net.Reference)
+			fmt.Fprintf(tw, "%s\t", net.Name)
+			fmt.Fprintf(tw, "-\t")
+			fmt.Fprintf(tw, "\n")
+			for _, pg := range net.Portgroups {
+				fmt.Fprintf(tw, "    %s\n", pg)
+			}
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t", net.Reference)
+		fmt.Fprintf(tw, "%s\t", net.Name)
+		fmt.Fprintf(tw, "%t\t", *net.Accessible)
+		fmt.Fprintf(tw, "\n")
+	}
+	fmt.Fprintf(tw, "\n")
+	return tw.Flush()
+}
+
+// Collect retrieves the properties of the network resources found by
+// Search. It returns a NetworkList.
+func (listnets *ListNets) Collect(ctx context.Context, p ...string) (Result, error) {
 	var nets []mo.Network
 	var dvs []mo.DistributedVirtualSwitch
 	var dvpg []mo.DistributedVirtualPortgroup
@@ -92,53 +162,70 @@ func (listnets *ListNets) Print(p ...string) {
 	if len(p) == 0 {
 		p = []string{"summary"}
 	}
-	if pc, err := property.DefaultCollector(listnets.client.Client).Create(listnets.ctx); err == nil {
-		log.Debug("Printing information ...")
-		tw := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
-		fmt.Fprintf(tw, "\n")
-		fmt.Fprintf(tw, "Reference\tName\tAccessible\n")
-		fmt.Fprintf(tw, "---------\t----\t----------\n")
-		if err := pc.Retrieve(listnets.ctx, listnets.refsNet, p, &nets); err != nil {
-			log.Errorf("Error retrieving Network information from references: %s", err)
-		} else {
-			for _, net := range nets {
-				s := net.Summary.GetNetworkSummary()
-				fmt.Fprintf(tw, "%s\t", net.Reference())
-				fmt.Fprintf(tw, "%s\t", s.Name)
-				fmt.Fprintf(tw, "%t\t", s.Accessible)
-				fmt.Fprintf(tw, "\n")
-			}
+	pc, err := listnets.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Collecting information ...")
+	list := NetworkList{}
+	if len(listnets.refsNet) > 0 {
+		if err := pc.Retrieve(ctx, listnets.refsNet, p, &nets); err != nil {
+			return nil, wrapError(err, "Error retrieving Network information from references")
 		}
-		if err := pc.Retrieve(listnets.ctx, listnets.refsDVPG, p, &dvpg); err != nil {
-			log.Errorf("Error retrieving DVPG information from references: %s", err)
-		} else {
-			for _, net := range dvpg {
-				s := net.Summary.GetNetworkSummary()
-				fmt.Fprintf(tw, "%s\t", net.Reference())
-				fmt.Fprintf(tw, "%s\t", s.Name)
-				fmt.Fprintf(tw, "%t\t", s.Accessible)
-				fmt.Fprintf(tw, "\n")
-			}
+	}
+	for _, net := range nets {
+		n := NetworkInfo{
+			Reference: net.Reference().String(),
+			Name:      net.Name,
+			Kind:      "Network",
 		}
-		if err := pc.Retrieve(listnets.ctx, listnets.refsDVS, p, &dvs); err != nil {
-			log.Errorf("Error retrieving DVS information from references: %s", err)
-		} else {
-			fmt.Fprintf(tw, "\n")
-			for _, net := range dvs {
-				fmt.Fprintf(tw, "%s\t", net.Reference())
-				fmt.Fprintf(tw, "%s\t", net.Name)
-				fmt.Fprintf(tw, "-\t")
-				if contains("summary", p) {
-					fmt.Fprintf(tw, "\n")
-					for _, pg := range net.Summary.PortgroupName {
-						fmt.Fprintf(tw, "    %s\n", pg)
-					}
-				} else {
-					fmt.Fprintf(tw, "\n")
-				}
-			}
+		if contains("summary", p) && net.Summary != nil {
+			s := net.Summary.GetNetworkSummary()
+			accessible := s.Accessible
+			n.Name = s.Name
+			n.Accessible = &accessible
+		}
+		list = append(list, n)
+	}
+	if len(listnets.refsDVPG) > 0 {
+		if err := pc.Retrieve(ctx, listnets.refsDVPG, p, &dvpg); err != nil {
+			return nil, wrapError(err, "Error retrieving DVPG information from references")
 		}
-		fmt.Fprintf(tw, "\n")
-		tw.Flush()
 	}
+	for _, net := range dvpg {
+		n := NetworkInfo{
+			Reference: net.Reference().String(),
+			Name:      net.Name,
+			Kind:      "DistributedVirtualPortgroup",
+		}
+		if contains("summary", p) && net.Summary != nil {
+			s := net.Summary.GetNetworkSummary()
+			accessible := s.Accessible
+			n.Name = s.Name
+			n.Accessible = &accessible
+		}
+		list = append(list, n)
+	}
+	if len(listnets.refsDVS) > 0 {
+		if err := pc.Retrieve(ctx, listnets.refsDVS, p, &dvs); err != nil {
+			return nil, wrapError(err, "Error retrieving DVS information from references")
+		}
+	}
+	for _, net := range dvs {
+		n := NetworkInfo{
+			Reference: net.Reference().String(),
+			Name:      net.Name,
+			Kind:      "VmwareDistributedVirtualSwitch",
+		}
+		if contains("summary", p) {
+			n.Portgroups = net.Summary.PortgroupName
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+// Print dumps a table with the results
+func (listnets *ListNets) Print(p ...string) error {
+	return listnets.print(listnets.Collect(listnets.ctx, p...))
 }
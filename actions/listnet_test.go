@@ -0,0 +1,54 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestListNets(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listnets, err := NewListNets(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listnets.Close()
+	list := collect(t, listnets).(NetworkList)
+	kinds := map[string]int{}
+	for _, n := range list {
+		kinds[n.Kind]++
+	}
+	if kinds["Network"] == 0 || kinds["DistributedVirtualPortgroup"] == 0 {
+		t.Errorf("Unexpected network resources: %v", kinds)
+	}
+	if out := render(t, OutputTable, list); !strings.Contains(out, "VM Network") {
+		t.Errorf("Network missing in table output:\n%s", out)
+	}
+	r, err := listnets.Collect(context.Background(), "name")
+	if err != nil {
+		t.Fatalf("Error collecting the names: %s", err)
+	}
+	for _, n := range r.(NetworkList) {
+		if n.Name == "" || n.Accessible != nil {
+			t.Errorf("Unexpected network without summary: %+v", n)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-playground/log"
+	"github.com/vmware/govmomi/vim25/mo"
+	"golang.org/x/net/context"
+)
+
+// Kinds of orphans
+const (
+	// OrphanVM is a VM managed by Nova unknown to Nova
+	OrphanVM = "orphan"
+	// OrphanUnmanaged is a VM known by Nova but not managed by it
+	OrphanUnmanaged = "unmanaged"
+	// OrphanMissing is a Nova instance without VM
+	OrphanMissing = "missing"
+)
+
+// OrphanInfo is a VM or a Nova instance which only exists on one side.
+// Reference, Name and PowerState are empty for the missing instances.
+type OrphanInfo struct {
+	Kind         string `json:"kind" yaml:"kind"`
+	InstanceUUID string `json:"instance_uuid" yaml:"instance_uuid"`
+	Reference    string `json:"reference" yaml:"reference"`
+	Name         string `json:"name" yaml:"name"`
+	PowerState   string `json:"power_state" yaml:"power_state"`
+	Project      string `json:"project" yaml:"project"`
+}
+
+// OrphanList is the data collected by the orphans action
+type OrphanList []OrphanInfo
+
+// Header returns the columns of the orphans table
+func (l OrphanList) Header() []string {
+	return []string{"Kind", "InstanceUuid", "Reference", "Name", "PowerState", "Project"}
+}
+
+// Rows returns one record per orphan
+func (l OrphanList) Rows() [][]string {
+	rows := [][]string{}
+	for _, o := range l {
+		rows = append(rows, []string{o.Kind, o.InstanceUUID, o.Reference, o.Name, o.PowerState, o.Project})
+	}
+	return rows
+}
+
+// WriteTable renders the orphans as a table
+func (l OrphanList) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Kind\tInstanceUuid\tReference\tName\tPowerState\tProject\n")
+	fmt.Fprintf(tw, "----\t------------\t---------\t----\t----------\t-------\n")
+	for _, o := range l {
+		fmt.Fprintf(tw, "%s\t", o.Kind)
+		fmt.Fprintf(tw, "%s\t", o.InstanceUUID)
+		fmt.Fprintf(tw, "%s\t", o.Reference)
+		fmt.Fprintf(tw, "%s\t", o.Name)
+		fmt.Fprintf(tw, "%s\t", o.PowerState)
+		fmt.Fprintf(tw, "%s\t", o.Project)
+		fmt.Fprintf(tw, "\n")
+	}
+	fmt.Fprintf(tw, "\n")
+	return tw.Flush()
+}
+
+// ListOrphans represents a class to compare the VMs of the datacenter
+// with the instances known by Nova
+type ListOrphans struct {
+	*ListVMs
+	uuids []string
+}
+
+// NewListOrphans is the constructor
+func NewListOrphans(c *Connection, dc string, ctx context.Context) (*ListOrphans, error) {
+	listorphans := ListOrphans{}
+	l, err := NewListVMs(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	listorphans.ListVMs = l
+	log.Debug("ListOrphans constructor")
+	return &listorphans, nil
+}
+
+// ReadUUIDs reads the Nova instance UUIDs, one per line, like the output
+// of `nova list --all-tenants --fields id --minimal`. Only the first word
+// of each line is used, empty lines and lines starting with # are skipped.
+func ReadUUIDs(r io.Reader) ([]string, error) {
+	uuids := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		uuids = append(uuids, strings.ToLower(fields[0]))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return uuids, nil
+}
+
+// SetUUIDs sets the UUIDs of the instances known by Nova
+func (listorphans *ListOrphans) SetUUIDs(uuids []string) {
+	listorphans.uuids = uuids
+}
+
+// orphans compares the VMs with the Nova instance UUIDs
+func orphans(vms []mo.VirtualMachine, uuids []string) OrphanList {
+	nova := make(map[string]bool)
+	for _, uuid := range uuids {
+		nova[strings.ToLower(uuid)] = false
+	}
+	list := OrphanList{}
+	for _, vm := range vms {
+		config := vm.Summary.Config
+		uuid := strings.ToLower(config.InstanceUuid)
+		managed := config.ManagedBy != nil && config.ManagedBy.ExtensionKey == NovaExtensionKey
+		_, known := nova[uuid]
+		if known {
+			nova[uuid] = true
+		}
+		o := OrphanInfo{
+			InstanceUUID: uuid,
+			Reference:    vm.Reference().Value,
+			Name:         vm.Name,
+			PowerState:   string(vm.Summary.Runtime.PowerState),
+		}
+		if a := ParseNovaAnnotation(config.Annotation); a != nil {
+			o.Project = a.ProjectName
+		}
+		switch {
+		case managed && !known:
+			o.Kind = OrphanVM
+		case known && !managed:
+			o.Kind = OrphanUnmanaged
+		default:
+			continue
+		}
+		list = append(list, o)
+	}
+	for _, uuid := range uuids {
+		uuid = strings.ToLower(uuid)
+		if found, ok := nova[uuid]; ok && !found {
+			list = append(list, OrphanInfo{Kind: OrphanMissing, InstanceUUID: uuid})
+			// Report duplicated UUIDs only once
+			nova[uuid] = true
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Kind < list[j].Kind })
+	return list
+}
+
+// Collect compares the VMs found by Search with the Nova instances given
+// by SetUUIDs. It returns an OrphanList.
+func (listorphans *ListOrphans) Collect(ctx context.Context, p ...string) (Result, error) {
+	var vms []mo.VirtualMachine
+
+	if len(listorphans.uuids) == 0 {
+		return nil, newError(ErrUnknown, nil, "No Nova instance UUIDs to compare with")
+	}
+	if len(p) == 0 {
+		p = []string{"name", "summary"}
+	}
+	pc, err := listorphans.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Collecting information ...")
+	if len(listorphans.refs) > 0 {
+		if err := pc.Retrieve(ctx, listorphans.refs, p, &vms); err != nil {
+			return nil, wrapError(err, "Error retrieving information from references")
+		}
+	}
+	return orphans(vms, listorphans.uuids), nil
+}
+
+// Print dumps a table with the results
+func (listorphans *ListOrphans) Print(p ...string) error {
+	return listorphans.print(listorphans.Collect(listorphans.ctx, p...))
+}
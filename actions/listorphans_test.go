@@ -0,0 +1,85 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+func TestOrphans(t *testing.T) {
+	uuids, err := ReadUUIDs(strings.NewReader("# nova list\nAAAA-1\n\nbbbb-2 web-01\ncccc-3\ncccc-3\n"))
+	if err != nil || len(uuids) != 4 || uuids[0] != "aaaa-1" {
+		t.Fatalf("Unexpected UUIDs: %v (%v)", uuids, err)
+	}
+	vm := func(uuid string, managed bool) mo.VirtualMachine {
+		vm := novaVM("web", 1, 1024, types.VirtualMachinePowerStatePoweredOn)
+		vm.Summary.Config.InstanceUuid = uuid
+		if !managed {
+			vm.Summary.Config.ManagedBy = nil
+		}
+		return vm
+	}
+	list := orphans([]mo.VirtualMachine{
+		vm("aaaa-1", true),
+		vm("bbbb-2", false),
+		vm("dddd-4", true),
+		vm("eeee-5", false),
+	}, uuids)
+	kinds := map[string]string{}
+	for _, o := range list {
+		kinds[o.InstanceUUID] = o.Kind
+	}
+	expected := map[string]string{"bbbb-2": OrphanUnmanaged, "cccc-3": OrphanMissing, "dddd-4": OrphanVM}
+	if len(list) != len(expected) {
+		t.Errorf("Unexpected orphans: %+v", list)
+	}
+	for uuid, kind := range expected {
+		if kinds[uuid] != kind {
+			t.Errorf("Expected %s to be %s, got %q", uuid, kind, kinds[uuid])
+		}
+	}
+}
+
+func TestListOrphans(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listorphans, err := NewListOrphans(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listorphans.Close()
+	if _, err := listorphans.Search(); err != nil {
+		t.Fatalf("Error searching: %s", err)
+	}
+	if _, err := listorphans.Collect(context.Background()); err == nil {
+		t.Error("Collect without Nova instances should fail")
+	}
+	// The simulator VMs are not managed by Nova, so all are missing
+	listorphans.SetUUIDs([]string{"aaaa-1", "bbbb-2"})
+	list, err := listorphans.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Error collecting: %s", err)
+	}
+	if l := list.(OrphanList); len(l) != 2 || l[0].Kind != OrphanMissing {
+		t.Errorf("Unexpected orphans: %+v", l)
+	}
+}
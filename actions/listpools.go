@@ -0,0 +1,271 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-playground/log"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+// PoolAllocation is the CPU (MHz) or memory (MB) allocation of a resource
+// pool. A Limit of -1 means unlimited.
+type PoolAllocation struct {
+	Reservation           int64  `json:"reservation" yaml:"reservation"`
+	Limit                 int64  `json:"limit" yaml:"limit"`
+	Shares                int32  `json:"shares" yaml:"shares"`
+	Level                 string `json:"level" yaml:"level"`
+	ExpandableReservation bool   `json:"expandable_reservation" yaml:"expandable_reservation"`
+}
+
+// PoolInfo is a resource pool with its child pools and the VMs directly
+// under it
+type PoolInfo struct {
+	Reference string         `json:"reference" yaml:"reference"`
+	Name      string         `json:"name" yaml:"name"`
+	CPU       PoolAllocation `json:"cpu" yaml:"cpu"`
+	Memory    PoolAllocation `json:"memory" yaml:"memory"`
+	VMs       []EntityRef    `json:"vms" yaml:"vms"`
+	Pools     []PoolInfo     `json:"pools" yaml:"pools"`
+}
+
+// ClusterPools is the resource pool hierarchy of a cluster
+type ClusterPools struct {
+	Reference string   `json:"reference" yaml:"reference"`
+	Name      string   `json:"name" yaml:"name"`
+	Root      PoolInfo `json:"root" yaml:"root"`
+}
+
+// PoolTree is the data collected by the pools action
+type PoolTree []ClusterPools
+
+func (a *PoolAllocation) limit(unit string) string {
+	if a.Limit < 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d %s", a.Limit, unit)
+}
+
+func (a *PoolAllocation) shares() string {
+	return fmt.Sprintf("%s(%d)", a.Level, a.Shares)
+}
+
+func (pool *PoolInfo) writeTable(tw io.Writer, prefix string) {
+	fmt.Fprintf(tw, "%s\t", pool.Name)
+	fmt.Fprintf(tw, "%d MHz\t", pool.CPU.Reservation)
+	fmt.Fprintf(tw, "%s\t", pool.CPU.limit("MHz"))
+	fmt.Fprintf(tw, "%s\t", pool.CPU.shares())
+	fmt.Fprintf(tw, "%d MB\t", pool.Memory.Reservation)
+	fmt.Fprintf(tw, "%s\t", pool.Memory.limit("MB"))
+	fmt.Fprintf(tw, "%s\t", pool.Memory.shares())
+	fmt.Fprintf(tw, "%t/%t\t", pool.CPU.ExpandableReservation, pool.Memory.ExpandableReservation)
+	fmt.Fprintf(tw, "\n")
+	for i, vm := range pool.VMs {
+		branch := "├─ "
+		if i == len(pool.VMs)-1 && len(pool.Pools) == 0 {
+			branch = "└─ "
+		}
+		fmt.Fprintf(tw, "%s%svm: %s\t\t\t\t\t\t\t\t\n", prefix, branch, vm.Name)
+	}
+	for i := range pool.Pools {
+		branch, indent := "├─ ", "│  "
+		if i == len(pool.Pools)-1 {
+			branch, indent = "└─ ", "   "
+		}
+		fmt.Fprintf(tw, "%s%s", prefix, branch)
+		pool.Pools[i].writeTable(tw, prefix+indent)
+	}
+}
+
+// WriteTable renders the resource pools of each cluster as a tree
+func (t PoolTree) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Name\tCpuReservation\tCpuLimit\tCpuShares\tMemReservation\tMemLimit\tMemShares\tExpandable\n")
+	fmt.Fprintf(tw, "----\t--------------\t--------\t---------\t--------------\t--------\t---------\t----------\n")
+	for i := range t {
+		fmt.Fprintf(tw, "%s (%s)\t\t\t\t\t\t\t\t\n", t[i].Name, t[i].Reference)
+		fmt.Fprintf(tw, "└─ ")
+		t[i].Root.writeTable(tw, "   ")
+		fmt.Fprintf(tw, "\n")
+	}
+	return tw.Flush()
+}
+
+// ListPools represents a class to show the resource pools of the clusters
+type ListPools struct {
+	*base
+	refs []types.ManagedObjectReference
+	view types.ManagedObjectReference
+}
+
+// NewListPools is the constructor
+func NewListPools(c *Connection, dc string, ctx context.Context) (*ListPools, error) {
+	listpools := ListPools{}
+	b, err := newBase(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	listpools.base = b
+	log.Debug("ListPools constructor")
+	return &listpools, nil
+}
+
+// Search gets the references of the clusters from VCenter, from all the
+// folders of the datacenter, and creates a view with all the resource
+// pools to get them with a single traversal.
+// It will return the number of references found.
+func (listpools *ListPools) Search(s ...string) (int, error) {
+	log.Debugf("Gathering Cluster references with filter: %s", strings.Join(s, ", "))
+	finder := find.NewFinder(listpools.client.Client, true)
+	dc, err := finder.DatacenterOrDefault(listpools.ctx, listpools.dc)
+	if err != nil {
+		return 0, datacenterError(err, listpools.dc)
+	}
+	refs, err := listpools.containerView(dc.Reference(), "ClusterComputeResource")
+	if err != nil {
+		return 0, err
+	}
+	listpools.refs = append(listpools.refs, refs...)
+	if listpools.view, err = listpools.createView(dc.Reference(), "ResourcePool"); err != nil {
+		return 0, err
+	}
+	return len(refs), nil
+}
+
+func allocation(a *types.ResourceAllocationInfo) PoolAllocation {
+	pa := PoolAllocation{Limit: -1}
+	if a.Reservation != nil {
+		pa.Reservation = *a.Reservation
+	}
+	if a.Limit != nil {
+		pa.Limit = *a.Limit
+	}
+	if a.ExpandableReservation != nil {
+		pa.ExpandableReservation = *a.ExpandableReservation
+	}
+	if a.Shares != nil {
+		pa.Shares = a.Shares.Shares
+		pa.Level = string(a.Shares.Level)
+	}
+	return pa
+}
+
+// buildPool returns the resource pool with all its descendants, children
+// has the pools of each parent. The names of the VMs are set afterwards by
+// Collect, vms gets all their references.
+func buildPool(rp *mo.ResourcePool, children map[string][]*mo.ResourcePool, vms *[]types.ManagedObjectReference) PoolInfo {
+	pool := PoolInfo{
+		Reference: rp.Reference().Value,
+		Name:      rp.Name,
+		CPU:       allocation(&rp.Config.CpuAllocation),
+		Memory:    allocation(&rp.Config.MemoryAllocation),
+		VMs:       []EntityRef{},
+		Pools:     []PoolInfo{},
+	}
+	for _, vm := range rp.Vm {
+		pool.VMs = append(pool.VMs, EntityRef{Reference: vm.Value})
+		*vms = append(*vms, vm)
+	}
+	for _, child := range children[pool.Reference] {
+		pool.Pools = append(pool.Pools, buildPool(child, children, vms))
+	}
+	return pool
+}
+
+func setVMNames(pool *PoolInfo, names map[string]string) {
+	for i := range pool.VMs {
+		pool.VMs[i].Name = names[pool.VMs[i].Reference]
+	}
+	for i := range pool.Pools {
+		setVMNames(&pool.Pools[i], names)
+	}
+}
+
+// Collect gets all the resource pools of the view and builds the hierarchy
+// of the clusters found by Search. It returns a PoolTree.
+func (listpools *ListPools) Collect(ctx context.Context, p ...string) (Result, error) {
+	var clusters []mo.ClusterComputeResource
+	var rps []mo.ResourcePool
+	var vms []mo.VirtualMachine
+	var vmRefs []types.ManagedObjectReference
+
+	if len(p) == 0 {
+		p = []string{"name", "resourcePool"}
+	}
+	if len(listpools.refs) == 0 {
+		return PoolTree{}, nil
+	}
+	pc, err := listpools.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Collecting information ...")
+	if err := pc.Retrieve(ctx, listpools.refs, p, &clusters); err != nil {
+		return nil, wrapError(err, "Error retrieving cluster information from references")
+	}
+	ps := []string{"name", "parent", "config", "vm"}
+	if err := listpools.retrieveView(ctx, pc, listpools.view, "ResourcePool", ps, &rps); err != nil {
+		return nil, err
+	}
+	pools := make(map[string]*mo.ResourcePool)
+	children := make(map[string][]*mo.ResourcePool)
+	for i := range rps {
+		rp := &rps[i]
+		pools[rp.Reference().Value] = rp
+		if rp.Parent != nil {
+			children[rp.Parent.Value] = append(children[rp.Parent.Value], rp)
+		}
+	}
+	tree := PoolTree{}
+	for _, cluster := range clusters {
+		if cluster.ResourcePool == nil {
+			continue
+		}
+		root, ok := pools[cluster.ResourcePool.Value]
+		if !ok {
+			continue
+		}
+		tree = append(tree, ClusterPools{
+			Reference: cluster.Reference().Value,
+			Name:      cluster.Name,
+			Root:      buildPool(root, children, &vmRefs),
+		})
+	}
+	if len(vmRefs) > 0 {
+		if err := pc.Retrieve(ctx, vmRefs, []string{"name"}, &vms); err != nil {
+			return nil, wrapError(err, "Error retrieving VM names")
+		}
+		names := make(map[string]string)
+		for _, vm := range vms {
+			names[vm.Reference().Value] = vm.Name
+		}
+		for i := range tree {
+			setVMNames(&tree[i].Root, names)
+		}
+	}
+	return tree, nil
+}
+
+// Print dumps a tree with the results
+func (listpools *ListPools) Print(p ...string) error {
+	return listpools.print(listpools.Collect(listpools.ctx, p...))
+}
@@ -0,0 +1,46 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestListPools(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listpools, err := NewListPools(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listpools.Close()
+	tree := collect(t, listpools).(PoolTree)
+	if len(tree) != 1 {
+		t.Fatalf("Expected one cluster, got %v", tree)
+	}
+	root := tree[0].Root
+	if root.Name != "Resources" || len(root.VMs) == 0 || root.VMs[0].Name == "" {
+		t.Errorf("Unexpected root resource pool: %+v", root)
+	}
+	out := render(t, OutputTable, tree)
+	if !strings.Contains(out, "└─ Resources") || !strings.Contains(out, "vm: ") {
+		t.Errorf("Unexpected tree output:\n%s", out)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-playground/log"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+// ProjectUsage is the resource usage of the VMs of an OpenStack project.
+// Memory and storage are in bytes, the VMs not powered on (off or
+// suspended) are counted in PoweredOff.
+type ProjectUsage struct {
+	ProjectID   string `json:"project_id" yaml:"project_id"`
+	ProjectName string `json:"project_name" yaml:"project_name"`
+	VMs         int    `json:"vms" yaml:"vms"`
+	VCPUs       int32  `json:"vcpus" yaml:"vcpus"`
+	Memory      int64  `json:"memory" yaml:"memory"`
+	Committed   int64  `json:"committed" yaml:"committed"`
+	Uncommitted int64  `json:"uncommitted" yaml:"uncommitted"`
+	PoweredOn   int    `json:"powered_on" yaml:"powered_on"`
+	PoweredOff  int    `json:"powered_off" yaml:"powered_off"`
+}
+
+// ProjectList is the data collected by the projects action
+type ProjectList []ProjectUsage
+
+// Header returns the columns of the projects table
+func (l ProjectList) Header() []string {
+	return []string{"ProjectId", "Project", "VMs", "vCPUs", "Memory", "Committed", "Uncommitted", "PoweredOn", "PoweredOff"}
+}
+
+// Rows returns one record per project, memory and storage in bytes
+func (l ProjectList) Rows() [][]string {
+	rows := [][]string{}
+	for _, p := range l {
+		rows = append(rows, []string{
+			p.ProjectID,
+			p.ProjectName,
+			strconv.Itoa(p.VMs),
+			strconv.Itoa(int(p.VCPUs)),
+			strconv.FormatInt(p.Memory, 10),
+			strconv.FormatInt(p.Committed, 10),
+			strconv.FormatInt(p.Uncommitted, 10),
+			strconv.Itoa(p.PoweredOn),
+			strconv.Itoa(p.PoweredOff),
+		})
+	}
+	return rows
+}
+
+// WriteTable renders the projects as a table
+func (l ProjectList) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "ProjectId\tProject\tVMs\tvCPUs\tMemory\tCommitted\tUncommitted\tPoweredOn\tPoweredOff\n")
+	fmt.Fprintf(tw, "---------\t-------\t---\t-----\t------\t---------\t-----------\t---------\t----------\n")
+	for _, p := range l {
+		id, name := p.ProjectID, p.ProjectName
+		if id == "" {
+			id = "-"
+		}
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(tw, "%s\t", id)
+		fmt.Fprintf(tw, "%s\t", name)
+		fmt.Fprintf(tw, "%d\t", p.VMs)
+		fmt.Fprintf(tw, "%d\t", p.VCPUs)
+		fmt.Fprintf(tw, "%s\t", units.ByteSize(p.Memory))
+		fmt.Fprintf(tw, "%s\t", units.ByteSize(p.Committed))
+		fmt.Fprintf(tw, "%s\t", units.ByteSize(p.Uncommitted))
+		fmt.Fprintf(tw, "%d\t", p.PoweredOn)
+		fmt.Fprintf(tw, "%d\t", p.PoweredOff)
+		fmt.Fprintf(tw, "\n")
+	}
+	fmt.Fprintf(tw, "\n")
+	return tw.Flush()
+}
+
+// ListProjects represents a class to report the resource usage of the
+// OpenStack projects
+type ListProjects struct {
+	*base
+	refs []types.ManagedObjectReference
+}
+
+// NewListProjects is the constructor
+func NewListProjects(c *Connection, dc string, ctx context.Context) (*ListProjects, error) {
+	listprojects := ListProjects{}
+	b, err := newBase(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	listprojects.base = b
+	log.Debug("ListProjects constructor")
+	return &listprojects, nil
+}
+
+// Search gets the references of all VMs of the datacenter, the VMs not
+// managed by OpenStack are discarded by Collect.
+// It will return the number of references found.
+func (listprojects *ListProjects) Search(s ...string) (int, error) {
+	log.Debugf("Gathering VM references with filter: %s", strings.Join(s, ", "))
+	finder := find.NewFinder(listprojects.client.Client, true)
+	dc, err := finder.DatacenterOrDefault(listprojects.ctx, listprojects.dc)
+	if err != nil {
+		return 0, datacenterError(err, listprojects.dc)
+	}
+	refs, err := listprojects.containerView(dc.Reference(), "VirtualMachine")
+	if err != nil {
+		return 0, err
+	}
+	listprojects.refs = append(listprojects.refs, refs...)
+	return len(refs), nil
+}
+
+// projectUsage groups the VMs managed by OpenStack Nova by the project of
+// their annotation, sorted by project name
+func projectUsage(vms []mo.VirtualMachine) ProjectList {
+	projects := make(map[string]*ProjectUsage)
+	for _, vm := range vms {
+		config := vm.Summary.Config
+		if config.ManagedBy == nil || config.ManagedBy.ExtensionKey != NovaExtensionKey {
+			continue
+		}
+		// The instances without a parseable annotation are grouped
+		// together in a project without ID
+		var id, name string
+		if nova := ParseNovaAnnotation(config.Annotation); nova != nil {
+			id, name = nova.ProjectID, nova.ProjectName
+		} else {
+			log.Debugf("VM %s managed by Nova without annotation", vm.Name)
+		}
+		project, ok := projects[id]
+		if !ok {
+			project = &ProjectUsage{ProjectID: id, ProjectName: name}
+			projects[id] = project
+		}
+		project.VMs++
+		project.VCPUs += config.NumCpu
+		project.Memory += int64(config.MemorySizeMB) * 1024 * 1024
+		if st := vm.Summary.Storage; st != nil {
+			project.Committed += st.Committed
+			project.Uncommitted += st.Uncommitted
+		}
+		if vm.Summary.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn {
+			project.PoweredOn++
+		} else {
+			project.PoweredOff++
+		}
+	}
+	list := ProjectList{}
+	for _, project := range projects {
+		list = append(list, *project)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].ProjectName == list[j].ProjectName {
+			return list[i].ProjectID < list[j].ProjectID
+		}
+		return list[i].ProjectName < list[j].ProjectName
+	})
+	return list
+}
+
+// vmProjectProperties are the properties of the VMs retrieved by the
+// projects action, only the parts of the summary used are requested
+var vmProjectProperties = []string{
+	"name",
+	"summary.config.managedBy",
+	"summary.config.annotation",
+	"summary.config.numCpu",
+	"summary.config.memorySizeMB",
+	"summary.storage.committed",
+	"summary.storage.uncommitted",
+	"summary.runtime.powerState",
+}
+
+// Collect groups the VMs managed by OpenStack Nova by the project of
+// their annotation. It returns a ProjectList sorted by project name.
+func (listprojects *ListProjects) Collect(ctx context.Context, p ...string) (Result, error) {
+	var vms []mo.VirtualMachine
+
+	if len(p) == 0 {
+		p = vmProjectProperties
+	}
+	if len(listprojects.refs) == 0 {
+		return ProjectList{}, nil
+	}
+	pc, err := listprojects.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Collecting information ...")
+	if err := pc.Retrieve(ctx, listprojects.refs, p, &vms); err != nil {
+		return nil, wrapError(err, "Error retrieving information from references")
+	}
+	return projectUsage(vms), nil
+}
+
+// Print dumps a table with the results
+func (listprojects *ListProjects) Print(p ...string) error {
+	return listprojects.print(listprojects.Collect(listprojects.ctx, p...))
+}
@@ -0,0 +1,73 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+func TestProjectUsage(t *testing.T) {
+	unmanaged := mo.VirtualMachine{}
+	unmanaged.Summary.Config.NumCpu = 8
+	list := projectUsage([]mo.VirtualMachine{
+		novaVM("web", 2, 2048, types.VirtualMachinePowerStatePoweredOn),
+		novaVM("db", 4, 8192, types.VirtualMachinePowerStatePoweredOn),
+		novaVM("web", 1, 1024, types.VirtualMachinePowerStateSuspended),
+		unmanaged,
+	})
+	if len(list) != 2 || list[0].ProjectName != "db" || list[1].ProjectName != "web" {
+		t.Fatalf("Unexpected projects: %+v", list)
+	}
+	web := ProjectUsage{
+		ProjectID:   "web-id",
+		ProjectName: "web",
+		VMs:         2,
+		VCPUs:       3,
+		Memory:      3072 * 1024 * 1024,
+		Committed:   20,
+		Uncommitted: 10,
+		PoweredOn:   1,
+		PoweredOff:  1,
+	}
+	if list[1] != web {
+		t.Errorf("Unexpected usage of project web: %+v", list[1])
+	}
+}
+
+func TestListProjects(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listprojects, err := NewListProjects(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listprojects.Close()
+	// The simulator VMs are not managed by Nova
+	list := collect(t, listprojects).(ProjectList)
+	if len(list) != 0 {
+		t.Errorf("Unexpected projects: %v", list)
+	}
+	out := render(t, OutputCSV, list)
+	if !strings.HasPrefix(out, "ProjectId,Project,VMs,vCPUs,Memory,") {
+		t.Errorf("Unexpected CSV output:\n%s", out)
+	}
+}
@@ -0,0 +1,272 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-playground/log"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+// Sort orders of the snapshots action
+const (
+	SortByName   = "name"
+	SortByOldest = "oldest"
+)
+
+// VMSnapshot is a snapshot of a VM with its child snapshots
+type VMSnapshot struct {
+	Reference   string       `json:"reference" yaml:"reference"`
+	Name        string       `json:"name" yaml:"name"`
+	Description string       `json:"description" yaml:"description"`
+	CreateTime  time.Time    `json:"create_time" yaml:"create_time"`
+	State       string       `json:"state" yaml:"state"`
+	Quiesced    bool         `json:"quiesced" yaml:"quiesced"`
+	Current     bool         `json:"current" yaml:"current"`
+	Snapshots   []VMSnapshot `json:"snapshots" yaml:"snapshots"`
+}
+
+// vmSnapshots converts snapshot.rootSnapshotList of the VM in a tree,
+// marking the current snapshot
+func vmSnapshots(vm *mo.VirtualMachine) []VMSnapshot {
+	if vm.Snapshot == nil {
+		return []VMSnapshot{}
+	}
+	var current types.ManagedObjectReference
+	if vm.Snapshot.CurrentSnapshot != nil {
+		current = *vm.Snapshot.CurrentSnapshot
+	}
+	var convert func(trees []types.VirtualMachineSnapshotTree) []VMSnapshot
+	convert = func(trees []types.VirtualMachineSnapshotTree) []VMSnapshot {
+		snapshots := []VMSnapshot{}
+		for _, t := range trees {
+			snapshots = append(snapshots, VMSnapshot{
+				Reference:   t.Snapshot.Value,
+				Name:        t.Name,
+				Description: t.Description,
+				CreateTime:  t.CreateTime,
+				State:       string(t.State),
+				Quiesced:    t.Quiesced,
+				Current:     t.Snapshot == current,
+				Snapshots:   convert(t.ChildSnapshotList),
+			})
+		}
+		return snapshots
+	}
+	return convert(vm.Snapshot.RootSnapshotList)
+}
+
+// walkSnapshots calls f for each snapshot of the tree
+func walkSnapshots(snapshots []VMSnapshot, f func(s *VMSnapshot)) {
+	for i := range snapshots {
+		f(&snapshots[i])
+		walkSnapshots(snapshots[i].Snapshots, f)
+	}
+}
+
+func writeSnapshots(tw io.Writer, snapshots []VMSnapshot, prefix string) {
+	for i, s := range snapshots {
+		branch, indent := "├─ ", "│  "
+		if i == len(snapshots)-1 {
+			branch, indent = "└─ ", "   "
+		}
+		flags := s.State
+		if s.Quiesced {
+			flags += ", quiesced"
+		}
+		if s.Current {
+			flags += ", current"
+		}
+		fmt.Fprintf(tw, "%s%s%s (%s):\t%s, %s\n", prefix, branch, s.Name, s.Reference, s.CreateTime.Format(time.RFC3339), flags)
+		if s.Description != "" {
+			fmt.Fprintf(tw, "%s%s   %s\t\n", prefix, indent, s.Description)
+		}
+		writeSnapshots(tw, s.Snapshots, prefix+indent)
+	}
+}
+
+// VMSnapshots is a VM with snapshots. AgeSeconds is the age of the oldest
+// snapshot when the information was collected.
+type VMSnapshots struct {
+	Reference  string       `json:"reference" yaml:"reference"`
+	Name       string       `json:"name" yaml:"name"`
+	Count      int          `json:"count" yaml:"count"`
+	Oldest     time.Time    `json:"oldest" yaml:"oldest"`
+	Newest     time.Time    `json:"newest" yaml:"newest"`
+	AgeSeconds int64        `json:"age_seconds" yaml:"age_seconds"`
+	Snapshots  []VMSnapshot `json:"snapshots" yaml:"snapshots"`
+}
+
+// age returns the age of the oldest snapshot in days and hours
+func (v *VMSnapshots) age() string {
+	d := time.Duration(v.AgeSeconds) * time.Second
+	return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+}
+
+// SnapshotList is the data collected by the snapshots action
+type SnapshotList []VMSnapshots
+
+// Header returns the columns of the snapshots table
+func (l SnapshotList) Header() []string {
+	return []string{"Reference", "Name", "Snapshots", "Oldest", "Newest", "AgeSeconds"}
+}
+
+// Rows returns one record per VM, times in RFC3339
+func (l SnapshotList) Rows() [][]string {
+	rows := [][]string{}
+	for _, v := range l {
+		rows = append(rows, []string{
+			v.Reference,
+			v.Name,
+			strconv.Itoa(v.Count),
+			v.Oldest.Format(time.RFC3339),
+			v.Newest.Format(time.RFC3339),
+			strconv.FormatInt(v.AgeSeconds, 10),
+		})
+	}
+	return rows
+}
+
+// WriteTable renders the VMs with snapshots as a table
+func (l SnapshotList) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Reference\tName\tSnapshots\tOldest\tNewest\tAge\n")
+	fmt.Fprintf(tw, "---------\t----\t---------\t------\t------\t---\n")
+	for i := range l {
+		v := &l[i]
+		fmt.Fprintf(tw, "%s\t", v.Reference)
+		fmt.Fprintf(tw, "%s\t", v.Name)
+		fmt.Fprintf(tw, "%d\t", v.Count)
+		fmt.Fprintf(tw, "%s\t", v.Oldest.Format(time.RFC3339))
+		fmt.Fprintf(tw, "%s\t", v.Newest.Format(time.RFC3339))
+		fmt.Fprintf(tw, "%s\t", v.age())
+		fmt.Fprintf(tw, "\n")
+	}
+	fmt.Fprintf(tw, "\n")
+	return tw.Flush()
+}
+
+// ListSnapshots represents a class to list the VMs with snapshots
+type ListSnapshots struct {
+	*base
+	refs []types.ManagedObjectReference
+	sort string
+}
+
+// NewListSnapshots is the constructor
+func NewListSnapshots(c *Connection, dc string, ctx context.Context) (*ListSnapshots, error) {
+	listsnapshots := ListSnapshots{sort: SortByName}
+	b, err := newBase(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	listsnapshots.base = b
+	log.Debug("ListSnapshots constructor")
+	return &listsnapshots, nil
+}
+
+// SetSort sets the order of the list: SortByName or SortByOldest
+func (listsnapshots *ListSnapshots) SetSort(order string) error {
+	switch order {
+	case SortByName, SortByOldest:
+		listsnapshots.sort = order
+		return nil
+	}
+	return fmt.Errorf("Unknown sort order '%s', use %s or %s", order, SortByName, SortByOldest)
+}
+
+// Search gets the references of all VMs of the datacenter, the VMs
+// without snapshots are discarded by Collect.
+// It will return the number of references found.
+func (listsnapshots *ListSnapshots) Search(s ...string) (int, error) {
+	log.Debugf("Gathering VM references with filter: %s", strings.Join(s, ", "))
+	finder := find.NewFinder(listsnapshots.client.Client, true)
+	dc, err := finder.DatacenterOrDefault(listsnapshots.ctx, listsnapshots.dc)
+	if err != nil {
+		return 0, datacenterError(err, listsnapshots.dc)
+	}
+	refs, err := listsnapshots.containerView(dc.Reference(), "VirtualMachine")
+	if err != nil {
+		return 0, err
+	}
+	listsnapshots.refs = append(listsnapshots.refs, refs...)
+	return len(refs), nil
+}
+
+// Collect retrieves the snapshots of the VMs found by Search.
+// It returns a SnapshotList.
+func (listsnapshots *ListSnapshots) Collect(ctx context.Context, p ...string) (Result, error) {
+	var vms []mo.VirtualMachine
+
+	if len(p) == 0 {
+		p = []string{"name", "snapshot.rootSnapshotList", "snapshot.currentSnapshot"}
+	}
+	if len(listsnapshots.refs) == 0 {
+		return SnapshotList{}, nil
+	}
+	pc, err := listsnapshots.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Collecting information ...")
+	if err := pc.Retrieve(ctx, listsnapshots.refs, p, &vms); err != nil {
+		return nil, wrapError(err, "Error retrieving snapshot information from references")
+	}
+	now := time.Now()
+	list := SnapshotList{}
+	for i := range vms {
+		snapshots := vmSnapshots(&vms[i])
+		if len(snapshots) == 0 {
+			continue
+		}
+		v := VMSnapshots{
+			Reference: vms[i].Reference().Value,
+			Name:      vms[i].Name,
+			Snapshots: snapshots,
+		}
+		walkSnapshots(snapshots, func(s *VMSnapshot) {
+			if v.Count == 0 || s.CreateTime.Before(v.Oldest) {
+				v.Oldest = s.CreateTime
+			}
+			if v.Count == 0 || s.CreateTime.After(v.Newest) {
+				v.Newest = s.CreateTime
+			}
+			v.Count++
+		})
+		v.AgeSeconds = int64(now.Sub(v.Oldest).Seconds())
+		list = append(list, v)
+	}
+	if listsnapshots.sort == SortByOldest {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Oldest.Before(list[j].Oldest) })
+	} else {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	return list, nil
+}
+
+// Print dumps a table with the results
+func (listsnapshots *ListSnapshots) Print(p ...string) error {
+	return listsnapshots.print(listsnapshots.Collect(listsnapshots.ctx, p...))
+}
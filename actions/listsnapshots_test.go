@@ -0,0 +1,92 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+func TestVMSnapshots(t *testing.T) {
+	created := time.Date(2016, 1, 1, 10, 0, 0, 0, time.UTC)
+	current := types.ManagedObjectReference{Type: "VirtualMachineSnapshot", Value: "snapshot-2"}
+	vm := mo.VirtualMachine{
+		Snapshot: &types.VirtualMachineSnapshotInfo{
+			CurrentSnapshot: &current,
+			RootSnapshotList: []types.VirtualMachineSnapshotTree{
+				{
+					Snapshot:   types.ManagedObjectReference{Type: "VirtualMachineSnapshot", Value: "snapshot-1"},
+					Name:       "base",
+					CreateTime: created,
+					State:      "poweredOff",
+					ChildSnapshotList: []types.VirtualMachineSnapshotTree{
+						{
+							Snapshot:    current,
+							Name:        "before-upgrade",
+							Description: "Upgrade to 16.04",
+							CreateTime:  created.Add(48 * time.Hour),
+							State:       "poweredOn",
+							Quiesced:    true,
+						},
+					},
+				},
+			},
+		},
+	}
+	snapshots := vmSnapshots(&vm)
+	if len(snapshots) != 1 || len(snapshots[0].Snapshots) != 1 {
+		t.Fatalf("Unexpected snapshot tree: %+v", snapshots)
+	}
+	if snapshots[0].Current || !snapshots[0].Snapshots[0].Current {
+		t.Errorf("Wrong current snapshot: %+v", snapshots)
+	}
+	d := VMDetail{Snapshots: snapshots}
+	out := render(t, OutputTable, &d)
+	if !strings.Contains(out, "└─ base (snapshot-1)") || !strings.Contains(out, "poweredOn, quiesced, current") {
+		t.Errorf("Unexpected snapshot tree output:\n%s", out)
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listsnapshots, err := NewListSnapshots(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listsnapshots.Close()
+	if err := listsnapshots.SetSort("newest"); err == nil {
+		t.Error("Unknown sort order accepted")
+	}
+	if err := listsnapshots.SetSort(SortByOldest); err != nil {
+		t.Errorf("Error setting sort order: %s", err)
+	}
+	// The simulator VMs have no snapshots
+	list := collect(t, listsnapshots).(SnapshotList)
+	if len(list) != 0 {
+		t.Errorf("Unexpected VMs with snapshots: %v", list)
+	}
+	out := render(t, OutputCSV, list)
+	if out != "Reference,Name,Snapshots,Oldest,Newest,AgeSeconds\n" {
+		t.Errorf("Unexpected CSV output:\n%s", out)
+	}
+}
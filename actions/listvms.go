@@ -15,118 +15,276 @@ package actions
 
 import (
 	"fmt"
-	"net/url"
-	"os"
+	"io"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/go-playground/log"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/property"
-	"github.com/vmware/govmomi/vim25/methods"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	"golang.org/x/net/context"
 )
 
+// vmSummaryProperties are the properties retrieved by the vms action,
+// only the parts of the summary shown are requested
+var vmSummaryProperties = []string{
+	"name",
+	"summary.guest",
+	"summary.runtime.powerState",
+	"summary.runtime.host",
+	"summary.config.memorySizeMB",
+	"summary.config.numCpu",
+	"summary.config.template",
+	"summary.config.annotation",
+}
+
+// VMSummary is one row of the vms action
+type VMSummary struct {
+	Reference  string `json:"reference" yaml:"reference"`
+	Name       string `json:"name" yaml:"name"`
+	HostName   string `json:"hostname" yaml:"hostname"`
+	GuestID    string `json:"guest_id" yaml:"guest_id"`
+	PowerState string `json:"power_state" yaml:"power_state"`
+	IPAddress  string `json:"ip_address" yaml:"ip_address"`
+	Host       string `json:"host" yaml:"host"`
+	MemoryMB   int32  `json:"memory_mb" yaml:"memory_mb"`
+	NumCPU     int32  `json:"num_cpu" yaml:"num_cpu"`
+	Template   bool   `json:"template" yaml:"template"`
+	Project    string `json:"project,omitempty" yaml:"project,omitempty"`
+	User       string `json:"user,omitempty" yaml:"user,omitempty"`
+	Flavor     string `json:"flavor,omitempty" yaml:"flavor,omitempty"`
+	Image      string `json:"image,omitempty" yaml:"image,omitempty"`
+}
+
+// VMList is the document of the vms action
+type VMList []VMSummary
+
+// Header returns the columns of the vms table
+func (l VMList) Header() []string {
+	return []string{"Reference", "Name", "HostName", "Guest", "PowerState", "IpAddress", "Project", "User", "Flavor", "Image"}
+}
+
+// Rows returns one record per VM
+func (l VMList) Rows() [][]string {
+	rows := [][]string{}
+	for _, v := range l {
+		rows = append(rows, []string{v.Reference, v.Name, v.HostName, v.GuestID, v.PowerState, v.IPAddress, v.Project, v.User, v.Flavor, v.Image})
+	}
+	return rows
+}
+
 // ListVMs represents a class to list information about all vms and templates
 // found in the datacenter
 type ListVMs struct {
 	*base
+	view   types.ManagedObjectReference
 	refs   []types.ManagedObjectReference
-	search []string
+	nova   NovaFilter
+	filter *Filter
 }
 
 // NewListVMs is the constructor
-func NewListVMs(u *url.URL, insecure bool, dc string, ctx context.Context) *ListVMs {
+func NewListVMs(c *Connection, dc string, ctx context.Context) (*ListVMs, error) {
 	listvms := ListVMs{}
-	listvms.base = newBase(u, insecure, dc, ctx)
+	b, err := newBase(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	listvms.base = b
 	log.Debug("ListVMs constructor")
-	return &listvms
+	return &listvms, nil
+}
+
+// SetNovaFilter sets the conditions on the Nova annotation that the VMs
+// have to match to be listed
+func (listvms *ListVMs) SetNovaFilter(f NovaFilter) {
+	listvms.nova = f
+}
+
+// SetFilter sets the filter expression that the VMs have to match to be
+// listed, nil lists all the VMs
+func (listvms *ListVMs) SetFilter(f *Filter) {
+	listvms.filter = f
 }
 
 // Search gets vm references from vcenter.
 // It accepts parameters to filter the search.
 // It will return the number of references found.
-func (listvms *ListVMs) Search(s ...string) int {
-	var viewManager mo.ViewManager
-
+func (listvms *ListVMs) Search(s ...string) (int, error) {
 	if len(s) == 0 {
 		s = []string{"*"}
 	}
 	log.Debugf("Gathering VM references with filter: %s", strings.Join(s, ", "))
-	for _, m := range s {
-		listvms.search = append(listvms.search, m)
-	}
 	finder := find.NewFinder(listvms.client.Client, true)
 	dc, err := finder.DatacenterOrDefault(listvms.ctx, listvms.dc)
 	if err != nil {
-		log.Panicf("Error getting datacenter: %s", err)
-	}
-	// http://www.geeklee.co.uk/object-properties-containerview-pyvmomi
-	// Create the view manager
-	if err := listvms.client.RetrieveOne(listvms.ctx, *listvms.client.ServiceContent.ViewManager, nil, &viewManager); err != nil {
-		log.Panicf("Error creating viewManager: %s", err)
-	}
-	// Create the CreateContentView request
-	req := types.CreateContainerView{
-		This:      viewManager.Reference(),
-		Container: dc.Reference(),
-		Type:      []string{"VirtualMachine"},
-		Recursive: true,
-	}
-	counter := 0
-	if res, err := methods.CreateContainerView(listvms.ctx, listvms.client.RoundTripper, &req); err == nil {
-		log.Debug("Getting list of vm references ...")
-		var containerView mo.ContainerView
-		if err := listvms.client.RetrieveOne(listvms.ctx, res.Returnval, nil, &containerView); err == nil {
-			// Assign each MORS type to a specific array
-			for _, mor := range containerView.View {
-				if mor.Type == "VirtualMachine" {
-					listvms.refs = append(listvms.refs, mor)
-					counter++
-				}
+		return 0, datacenterError(err, listvms.dc)
+	}
+	// The view is kept to retrieve the properties of all the VMs with a
+	// single traversal
+	if listvms.view, err = listvms.createView(dc.Reference(), "VirtualMachine"); err != nil {
+		return 0, err
+	}
+	refs, err := listvms.viewReferences(listvms.view, "VirtualMachine")
+	if err != nil {
+		return 0, err
+	}
+	listvms.refs = append(listvms.refs, refs...)
+	return len(refs), nil
+}
+
+// retrieve gets the properties of the VMs found by Search, from the
+// container view if there is one
+func (listvms *ListVMs) retrieve(ctx context.Context, pc *property.Collector, ps []string, dst interface{}) error {
+	if listvms.view.Value != "" {
+		return listvms.retrieveView(ctx, pc, listvms.view, "VirtualMachine", ps, dst)
+	}
+	if len(listvms.refs) == 0 {
+		return nil
+	}
+	if err := pc.Retrieve(ctx, listvms.refs, ps, dst); err != nil {
+		return wrapError(err, "Error retrieving information from references")
+	}
+	return nil
+}
+
+// WriteTable renders the VMs as a table
+func (l VMList) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 1, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Reference\tName\tHostName\tGuest\tPowerState\tIpAddress\tProject\tUser\tFlavor\tImage\n")
+	fmt.Fprintf(tw, "---------\t----\t--------\t-----\t----------\t---------\t-------\t----\t------\t-----\n")
+	for _, v := range l {
+		fmt.Fprintf(tw, "%s\t", v.Reference)
+		fmt.Fprintf(tw, "%s\t", strings.SplitN(v.Name, " ", 2)[0])
+		fmt.Fprintf(tw, "%s\t", v.HostName)
+		fmt.Fprintf(tw, "%s\t", v.GuestID)
+		fmt.Fprintf(tw, "%s\t", v.PowerState)
+		fmt.Fprintf(tw, "%s\t", v.IPAddress)
+		fmt.Fprintf(tw, "%s\t", v.Project)
+		fmt.Fprintf(tw, "%s\t", v.User)
+		fmt.Fprintf(tw, "%s\t", v.Flavor)
+		fmt.Fprintf(tw, "%s\t", v.Image)
+		fmt.Fprintf(tw, "\n")
+	}
+	fmt.Fprintf(tw, "\n")
+	return tw.Flush()
+}
+
+// hostNames returns the names of the hosts running the VMs, indexed by
+// reference value
+func (listvms *ListVMs) hostNames(ctx context.Context, pc *property.Collector, vms []mo.VirtualMachine) (map[string]string, error) {
+	var hosts []mo.HostSystem
+
+	names := make(map[string]string)
+	refs := []types.ManagedObjectReference{}
+	for _, vm := range vms {
+		if ref := vm.Summary.Runtime.Host; ref != nil {
+			if _, ok := names[ref.Value]; !ok {
+				names[ref.Value] = ""
+				refs = append(refs, *ref)
 			}
-		} else {
-			log.Panicf("Error retrieving references: %s", err)
 		}
-	} else {
-		log.Panicf("Error creating container view: %s", err)
 	}
-	return counter
+	if len(refs) == 0 {
+		return names, nil
+	}
+	if err := pc.Retrieve(ctx, refs, []string{"name"}, &hosts); err != nil {
+		return nil, wrapError(err, "Error retrieving host names")
+	}
+	for _, h := range hosts {
+		names[h.Reference().Value] = h.Name
+	}
+	return names, nil
 }
 
-// Print dumps a table with the results
-func (listvms *ListVMs) Print(p ...string) {
+// filterProperties adds to p the properties of the summary missing to
+// match the filters, they are applied to the fields of the summary
+func (listvms *ListVMs) filterProperties(p []string) []string {
+	if listvms.nova.IsEmpty() && listvms.filter == nil {
+		return p
+	}
+	ps := append([]string{}, p...)
+	for _, s := range vmSummaryProperties {
+		found := false
+		for _, q := range p {
+			if s == q || strings.HasPrefix(s, q+".") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ps = append(ps, s)
+		}
+	}
+	return ps
+}
+
+// Collect retrieves the properties of the VMs found by Search and
+// matching the Nova filter and the filter expression. The properties
+// needed by the filters are always retrieved. It returns a VMList.
+func (listvms *ListVMs) Collect(ctx context.Context, p ...string) (Result, error) {
 	var vms []mo.VirtualMachine
 
 	if len(p) == 0 {
-		p = []string{"name", "summary"}
+		p = vmSummaryProperties
 	}
-	if pc, err := property.DefaultCollector(listvms.client.Client).Create(listvms.ctx); err == nil {
-		log.Debug("Printing information ...")
-		tw := tabwriter.NewWriter(os.Stdout, 1, 0, 1, ' ', 0)
-		fmt.Fprintf(tw, "\n")
-		fmt.Fprintf(tw, "Reference\tName\tHostName\tGuest\tPowerState\tIpAddress\n")
-		fmt.Fprintf(tw, "---------\t----\t--------\t-----\t----------\t---------\n")
-		if err := pc.Retrieve(listvms.ctx, listvms.refs, p, &vms); err != nil {
-			log.Errorf("Error retrieving information from references: %s", err)
-		} else {
-			for _, vm := range vms {
-				fmt.Fprintf(tw, "%s\t", vm.Reference().Value)
-				fmt.Fprintf(tw, "%s\t", strings.SplitN(vm.Name, " ", 2)[0])
-				if contains("summary", p) {
-					fmt.Fprintf(tw, "%s\t", vm.Summary.Guest.HostName)
-					fmt.Fprintf(tw, "%s\t", vm.Summary.Guest.GuestId)
-					fmt.Fprintf(tw, "%s\t", vm.Summary.Runtime.PowerState)
-					fmt.Fprintf(tw, "%s\t", vm.Summary.Guest.IpAddress)
-				}
-				fmt.Fprintf(tw, "\n")
+	p = listvms.filterProperties(p)
+	pc, err := listvms.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Collecting information ...")
+	if err := listvms.retrieve(ctx, pc, p, &vms); err != nil {
+		return nil, err
+	}
+	hosts := make(map[string]string)
+	if hasProperty("summary", p) {
+		if hosts, err = listvms.hostNames(ctx, pc, vms); err != nil {
+			return nil, err
+		}
+	}
+	list := VMList{}
+	for _, vm := range vms {
+		v := VMSummary{
+			Reference: vm.Reference().Value,
+			Name:      vm.Name,
+		}
+		if hasProperty("summary", p) {
+			if vm.Summary.Guest != nil {
+				v.HostName = vm.Summary.Guest.HostName
+				v.GuestID = vm.Summary.Guest.GuestId
+				v.IPAddress = vm.Summary.Guest.IpAddress
+			}
+			v.PowerState = string(vm.Summary.Runtime.PowerState)
+			v.MemoryMB = vm.Summary.Config.MemorySizeMB
+			v.NumCPU = vm.Summary.Config.NumCpu
+			v.Template = vm.Summary.Config.Template
+			if ref := vm.Summary.Runtime.Host; ref != nil {
+				v.Host = hosts[ref.Value]
+			}
+			nova := ParseNovaAnnotation(vm.Summary.Config.Annotation)
+			if !listvms.nova.Match(nova) {
+				continue
+			}
+			if nova != nil {
+				v.Project = nova.ProjectName
+				v.User = nova.UserName
+				v.Flavor = nova.Flavor.Name
+				v.Image = nova.ImageID
 			}
 		}
-		fmt.Fprintf(tw, "\n")
-		tw.Flush()
-	} else {
-		log.Errorf("Error creating collector: %s", err)
+		if listvms.filter != nil && !listvms.filter.Match(&v) {
+			continue
+		}
+		list = append(list, v)
 	}
+	return list, nil
+}
+
+// Print dumps a table with the results
+func (listvms *ListVMs) Print(p ...string) error {
+	return listvms.print(listvms.Collect(listvms.ctx, p...))
 }
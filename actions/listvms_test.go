@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestListVMs(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listvms, err := NewListVMs(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listvms.Close()
+	list := collect(t, listvms, "*").(VMList)
+	names := map[string]VMSummary{}
+	for _, vm := range list {
+		names[vm.Name] = vm
+	}
+	vm, ok := names["DC0_H0_VM0"]
+	if !ok {
+		t.Fatalf("DC0_H0_VM0 not found in %v", list)
+	}
+	if vm.PowerState != "poweredOn" {
+		t.Errorf("Unexpected power state: %s", vm.PowerState)
+	}
+	var doc []map[string]interface{}
+	if err := json.Unmarshal([]byte(render(t, OutputJSON, list)), &doc); err != nil {
+		t.Fatalf("Invalid JSON output: %s", err)
+	}
+	if len(doc) != len(list) || doc[0]["power_state"] == nil {
+		t.Errorf("Unexpected JSON document: %v", doc)
+	}
+	if out := render(t, OutputYAML, list); !strings.Contains(out, "name: DC0_H0_VM0") {
+		t.Errorf("VM missing in YAML output:\n%s", out)
+	}
+	if out := render(t, OutputCSV, list); strings.Count(out, "\n") != len(list)+1 {
+		t.Errorf("Unexpected CSV output:\n%s", out)
+	}
+}
+
+func TestListVMsFilter(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listvms, err := NewListVMs(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listvms.Close()
+	f, err := ParseFilter("name~_h0_ && power=poweredOn")
+	if err != nil {
+		t.Fatalf("Error parsing filter: %s", err)
+	}
+	listvms.SetFilter(f)
+	list := collect(t, listvms, "*").(VMList)
+	if len(list) == 0 {
+		t.Fatal("No VMs matching the filter")
+	}
+	for _, vm := range list {
+		if !strings.HasPrefix(vm.Name, "DC0_H0_") || vm.Host == "" || vm.MemoryMB == 0 {
+			t.Errorf("Unexpected VM: %+v", vm)
+		}
+	}
+}
+
+func TestListVMsNovaFilter(t *testing.T) {
+	nova := &NovaAnnotation{ProjectName: "pe", ProjectID: "973b", UserName: "jriguera", Flavor: NovaFlavor{Name: "medium"}}
+	for _, c := range []struct {
+		filter NovaFilter
+		match  bool
+	}{
+		{NovaFilter{}, true},
+		{NovaFilter{Project: "PE"}, true},
+		{NovaFilter{Project: "973b", User: "jriguera"}, true},
+		{NovaFilter{Project: "pe", Flavor: "small"}, false},
+		{NovaFilter{Image: "0f1e"}, false},
+	} {
+		if c.filter.Match(nova) != c.match {
+			t.Errorf("Filter %+v should return %t", c.filter, c.match)
+		}
+	}
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listvms, err := NewListVMs(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listvms.Close()
+	// The simulator VMs are not managed by Nova
+	listvms.SetNovaFilter(NovaFilter{Project: "pe"})
+	if list := collect(t, listvms, "*").(VMList); len(list) != 0 {
+		t.Errorf("Unexpected VMs for the project: %v", list)
+	}
+	// The annotation is retrieved to apply the filter
+	r, err := listvms.Collect(context.Background(), "name")
+	if err != nil {
+		t.Fatalf("Error collecting the names: %s", err)
+	}
+	if list := r.(VMList); len(list) != 0 {
+		t.Errorf("Unexpected VMs for the project with the names: %v", list)
+	}
+}
@@ -0,0 +1,372 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/log"
+	"github.com/vmware/govmomi/find"
+	"golang.org/x/net/context"
+)
+
+// Leading columns of the results merged from several targets
+const (
+	ColumnVCenter    = "vCenter"
+	ColumnDatacenter = "Datacenter"
+)
+
+// MergedPart is the result of an action in one of the targets (vCenters
+// or datacenters) of Multi. Result is nil if the action failed there.
+type MergedPart struct {
+	Name   string
+	Result Result
+	Err    error
+}
+
+// MergedResult is the result of an action in several targets. The table
+// output has a section per target, the JSON and YAML outputs are an array
+// with an object per target: {<column>: name, result: ..., error: ...}.
+type MergedResult struct {
+	Column string
+	Parts  []MergedPart
+}
+
+// MergedTable is a MergedResult of Tabular results. The table output has a
+// section per target like MergedResult, the CSV output is a single table
+// with the name of the target in the leading column.
+type MergedTable struct {
+	MergedResult
+}
+
+func (m MergedResult) documents() []map[string]interface{} {
+	docs := []map[string]interface{}{}
+	for _, p := range m.Parts {
+		doc := map[string]interface{}{strings.ToLower(m.Column): p.Name}
+		if p.Err != nil {
+			doc["error"] = p.Err.Error()
+		} else {
+			doc["result"] = p.Result
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// MarshalJSON renders the array of targets
+func (m MergedResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.documents())
+}
+
+// MarshalYAML renders the array of targets
+func (m MergedResult) MarshalYAML() (interface{}, error) {
+	return m.documents(), nil
+}
+
+// WriteTable renders the result of each target after its name
+func (m MergedResult) WriteTable(w io.Writer) error {
+	for _, p := range m.Parts {
+		if p.Err != nil {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s: %s\n", m.Column, p.Name)
+		if err := p.Result.WriteTable(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Header returns the columns of the results with the leading column
+func (m MergedTable) Header() []string {
+	for _, p := range m.Parts {
+		if t, ok := p.Result.(Tabular); ok {
+			return append([]string{m.Column}, t.Header()...)
+		}
+	}
+	return []string{m.Column}
+}
+
+// Rows returns the rows of all the targets with their name
+func (m MergedTable) Rows() [][]string {
+	rows := [][]string{}
+	for _, p := range m.Parts {
+		t, ok := p.Result.(Tabular)
+		if !ok {
+			continue
+		}
+		for _, row := range t.Rows() {
+			rows = append(rows, append([]string{p.Name}, row...))
+		}
+	}
+	return rows
+}
+
+// Multi runs an action in several targets (vCenters or datacenters)
+// concurrently and merges their results. A target failing is reported,
+// but the results of the others are still printed.
+type Multi struct {
+	column    string
+	names     []string
+	actions   []Action
+	errs      []error
+	ctx       context.Context
+	formatter Formatter
+}
+
+// NewMulti creates the action of each target concurrently, calling its
+// constructor. It only fails if all the constructors fail, then the
+// actions already created are closed.
+func NewMulti(column string, names []string, constructors []func() (Action, error), ctx context.Context) (*Multi, error) {
+	m := Multi{
+		column:    column,
+		names:     names,
+		actions:   make([]Action, len(names)),
+		errs:      make([]error, len(names)),
+		ctx:       ctx,
+		formatter: tableFormatter{},
+	}
+	m.each(func(i int) {
+		a, err := constructors[i]()
+		if err != nil {
+			// The constructors return typed nil pointers
+			m.errs[i] = err
+			return
+		}
+		if m.ctx.Err() != nil {
+			// Interrupted while the other targets were connecting
+			a.Close()
+			m.errs[i] = m.ctx.Err()
+			return
+		}
+		m.actions[i] = a
+	})
+	log.Debugf("Multi constructor for %s", strings.Join(names, ", "))
+	if err := m.failed(); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return &m, nil
+}
+
+// IsDatacenterPattern returns true if the datacenter is a glob matching
+// several datacenters
+func IsDatacenterPattern(dc string) bool {
+	return strings.ContainsAny(dc, "*?[")
+}
+
+// NewMultiDatacenter creates the action for each datacenter matching the
+// pattern (e.g. "*" for all of them), calling the constructor with the
+// inventory path of the datacenter. The constructor should create the
+// actions with the same connection, so there is one session per vCenter.
+func NewMultiDatacenter(c *Connection, pattern string, ctx context.Context, newAction func(dc string) (Action, error)) (*Multi, error) {
+	finder := find.NewFinder(c.client.Client, true)
+	datacenters, err := finder.DatacenterList(ctx, pattern)
+	if err != nil {
+		return nil, datacenterError(err, pattern)
+	}
+	names := []string{}
+	constructors := []func() (Action, error){}
+	for _, dc := range datacenters {
+		path := dc.InventoryPath
+		names = append(names, dc.Name())
+		constructors = append(constructors, func() (Action, error) { return newAction(path) })
+	}
+	return NewMulti(ColumnDatacenter, names, constructors, ctx)
+}
+
+// each runs the function for each target with an action concurrently
+func (m *Multi) each(f func(i int)) {
+	var wg sync.WaitGroup
+	for i := range m.names {
+		if m.errs[i] != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// failed returns the error of the first target if all of them failed
+func (m *Multi) failed() error {
+	for _, err := range m.errs {
+		if err == nil {
+			return nil
+		}
+	}
+	if len(m.errs) == 0 {
+		return newError(ErrUnknown, nil, "No %s to query", m.column)
+	}
+	return m.errs[0]
+}
+
+// Close closes the actions of all the targets, it returns the first error
+func (m *Multi) Close() error {
+	errs := make([]error, len(m.names))
+	var wg sync.WaitGroup
+	for i, a := range m.actions {
+		if a == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, a Action) {
+			defer wg.Done()
+			errs[i] = a.Close()
+		}(i, a)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetOutput defines the format used by Print: table (default), json,
+// yaml or csv
+func (m *Multi) SetOutput(format string) error {
+	f, err := NewFormatter(format)
+	if err != nil {
+		return err
+	}
+	m.formatter = f
+	return nil
+}
+
+// Search runs the search in all the targets, it returns the number of
+// references found in all of them
+func (m *Multi) Search(s ...string) (int, error) {
+	counts := make([]int, len(m.names))
+	m.each(func(i int) {
+		counts[i], m.errs[i] = m.actions[i].Search(s...)
+	})
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total, m.failed()
+}
+
+// Collect retrieves the results of all the targets. It returns a
+// MergedTable if all of them are Tabular, otherwise a MergedResult.
+func (m *Multi) Collect(ctx context.Context, p ...string) (Result, error) {
+	results := make([]Result, len(m.names))
+	m.each(func(i int) {
+		results[i], m.errs[i] = m.actions[i].Collect(ctx, p...)
+	})
+	if err := m.failed(); err != nil {
+		return nil, err
+	}
+	merged := MergedResult{Column: m.column}
+	tabular := true
+	for i, name := range m.names {
+		merged.Parts = append(merged.Parts, MergedPart{name, results[i], m.errs[i]})
+		if m.errs[i] == nil {
+			_, ok := results[i].(Tabular)
+			tabular = tabular && ok
+		}
+	}
+	if tabular {
+		return MergedTable{merged}, nil
+	}
+	return merged, nil
+}
+
+// Print dumps the merged results and reports the targets which failed.
+// The objects not found in some targets are not failures, only if they
+// are not found anywhere.
+func (m *Multi) Print(p ...string) error {
+	result, err := m.Collect(m.ctx, p...)
+	if err != nil {
+		return err
+	}
+	if err := m.formatter.Format(os.Stdout, result); err != nil {
+		return fmt.Errorf("Error rendering output: %s", err)
+	}
+	failed, first := m.failures("")
+	out := os.Stdout
+	if _, ok := m.formatter.(tableFormatter); !ok {
+		out = os.Stderr
+	}
+	m.waitConsole(out, result)
+	if first != nil {
+		return newError(KindOf(first), nil, "Failed in %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// failures logs the errors of the targets, including the ones of the
+// nested Multi (the datacenters of a vCenter), named with the prefix.
+// It returns the names of the targets failed and the first error.
+func (m *Multi) failures(prefix string) ([]string, error) {
+	var failed []string
+	var first error
+	for i, name := range m.names {
+		name = prefix + name
+		err := m.errs[i]
+		switch {
+		case err == nil:
+			if child, ok := m.actions[i].(*Multi); ok {
+				f, e := child.failures(name + "/")
+				failed = append(failed, f...)
+				if first == nil {
+					first = e
+				}
+			}
+			continue
+		case KindOf(err) == ErrNotFound:
+			log.Debugf("%s: %s", name, err)
+			continue
+		}
+		log.Errorf("%s: %s", name, err)
+		failed = append(failed, name)
+		if first == nil {
+			first = err
+		}
+	}
+	return failed, first
+}
+
+// waitConsole keeps the sessions open for the console URLs given by the
+// show action in any of the targets. It returns true if it waited.
+func (m *Multi) waitConsole(out io.Writer, result Result) bool {
+	merged, ok := result.(MergedResult)
+	if !ok {
+		return false
+	}
+	for i, part := range merged.Parts {
+		switch a := m.actions[i].(type) {
+		case *ShowVM:
+			if hasConsoles(part.Result) {
+				a.waitConsole(out)
+				return true
+			}
+		case *Multi:
+			if part.Err == nil && a.waitConsole(out, part.Result) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/simulator"
+	"golang.org/x/net/context"
+)
+
+func TestMergedResult(t *testing.T) {
+	merged := MergedTable{MergedResult{
+		Column: ColumnVCenter,
+		Parts: []MergedPart{
+			{Name: "vc1", Result: VMList{{Reference: "vm-1", Name: "web-01"}}},
+			{Name: "vc2", Err: newError(ErrConnection, nil, "Cannot connect with vc2")},
+			{Name: "vc3", Result: VMList{{Reference: "vm-1", Name: "db-01"}, {Reference: "vm-2", Name: "db-02"}}},
+		},
+	}}
+	header := merged.Header()
+	if header[0] != ColumnVCenter || len(header) != len(VMList{}.Header())+1 {
+		t.Errorf("Unexpected header: %v", header)
+	}
+	rows := merged.Rows()
+	if len(rows) != 3 || rows[0][0] != "vc1" || rows[2][0] != "vc3" || rows[2][2] != "db-02" {
+		t.Errorf("Unexpected rows: %v", rows)
+	}
+	if out := render(t, OutputCSV, merged); strings.Count(out, "\n") != 4 {
+		t.Errorf("Unexpected CSV output:\n%s", out)
+	}
+	// Each target is rendered with its own table, like a single target
+	out := render(t, OutputTable, merged)
+	if !strings.Contains(out, "vCenter: vc3") || strings.Contains(out, "vc2") || !strings.Contains(out, render(t, OutputTable, merged.Parts[2].Result)) {
+		t.Errorf("Unexpected table output:\n%s", out)
+	}
+	var doc []map[string]interface{}
+	if err := json.Unmarshal([]byte(render(t, OutputJSON, merged)), &doc); err != nil {
+		t.Fatalf("Invalid JSON output: %s", err)
+	}
+	if len(doc) != 3 || doc[0]["vcenter"] != "vc1" || doc[1]["error"] == nil || doc[1]["result"] != nil {
+		t.Errorf("Unexpected JSON document: %v", doc)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	constructors := []func() (Action, error){
+		func() (Action, error) { return NewListVMs(c, "DC0", context.Background()) },
+		func() (Action, error) { return nil, newError(ErrConnection, nil, "Cannot connect with vc2") },
+		func() (Action, error) { return NewListVMs(c, "DC0", context.Background()) },
+	}
+	multi, err := NewMulti(ColumnVCenter, []string{"vc1", "vc2", "vc3"}, constructors, context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer multi.Close()
+	merged, ok := collect(t, multi, "*").(MergedTable)
+	if !ok {
+		t.Fatalf("Expected a MergedTable")
+	}
+	found := map[string]int{}
+	for _, row := range merged.Rows() {
+		found[row[0]]++
+	}
+	if found["vc1"] == 0 || found["vc1"] != found["vc3"] || found["vc2"] != 0 {
+		t.Errorf("Unexpected rows per vCenter: %v", found)
+	}
+	if merged.Parts[1].Err == nil {
+		t.Error("The error of vc2 is missing")
+	}
+	// All the vCenters failing is an error
+	failing := func() (Action, error) { return nil, newError(ErrConnection, nil, "Cannot connect") }
+	if m, err := NewMulti(ColumnVCenter, []string{"vc1", "vc2"}, []func() (Action, error){failing, failing}, context.Background()); m != nil || KindOf(err) != ErrConnection {
+		t.Errorf("Expected a connection error, got %v", err)
+	}
+	// The actions created after an interruption are closed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var listvms *ListVMs
+	interrupted := func() (Action, error) {
+		listvms, err = NewListVMs(c, "DC0", ctx)
+		return listvms, err
+	}
+	if m, err := NewMulti(ColumnVCenter, []string{"vc1"}, []func() (Action, error){interrupted}, ctx); m != nil || err == nil {
+		t.Errorf("Expected an error after the interruption, got %v", err)
+	}
+	if listvms == nil || listvms.conn != nil {
+		t.Errorf("Action created after the interruption not closed")
+	}
+}
+
+func TestMultiDatacenter(t *testing.T) {
+	model := simulator.VPX()
+	model.Datacenter = 2
+	if err := model.Create(); err != nil {
+		t.Fatalf("Error creating the simulator model: %s", err)
+	}
+	defer model.Remove()
+	server := model.Service.NewServer()
+	defer server.Close()
+	u := server.URL
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	// Without datacenter DatacenterOrDefault fails
+	listdss, err := NewListDSs(c, "", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listdss.Close()
+	if _, err := listdss.Search("*"); KindOf(err) != ErrDatacenterNotFound {
+		t.Errorf("Expected datacenter not found with several datacenters, got %v", err)
+	}
+	multi, err := NewMultiDatacenter(c, "*", context.Background(), func(dc string) (Action, error) {
+		return NewListDSs(c, dc, context.Background())
+	})
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer multi.Close()
+	// The datacenters share the session
+	for _, a := range multi.actions {
+		if a.(*ListDSs).client != c.client {
+			t.Errorf("Datacenter action with its own client")
+		}
+	}
+	merged := collect(t, multi, "*").(MergedTable)
+	if header := merged.Header(); header[0] != ColumnDatacenter {
+		t.Errorf("Unexpected header: %v", header)
+	}
+	found := map[string]bool{}
+	for _, row := range merged.Rows() {
+		found[row[0]] = true
+	}
+	if !found["DC0"] || !found["DC1"] {
+		t.Errorf("Expected datastores of DC0 and DC1, got %v", found)
+	}
+	if _, err := NewMultiDatacenter(c, "XX*", context.Background(), nil); KindOf(err) != ErrDatacenterNotFound {
+		t.Errorf("Expected datacenter not found, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/log"
+)
+
+// NovaExtensionKey is the ManagedBy extension of the VMs created by the
+// OpenStack Nova VMware driver
+const NovaExtensionKey = "org.openstack.compute"
+
+// NovaFlavor is the flavor of an OpenStack instance
+type NovaFlavor struct {
+	Name        string `json:"name" yaml:"name"`
+	MemoryMB    int    `json:"memory_mb" yaml:"memory_mb"`
+	VCPUs       int    `json:"vcpus" yaml:"vcpus"`
+	RootGB      int    `json:"root_gb" yaml:"root_gb"`
+	EphemeralGB int    `json:"ephemeral_gb" yaml:"ephemeral_gb"`
+	SwapMB      int    `json:"swap_mb" yaml:"swap_mb"`
+}
+
+// NovaAnnotation is the metadata written by the OpenStack Nova VMware driver
+// in the annotation of the instances. The unknown keys are kept in Extra.
+type NovaAnnotation struct {
+	Name        string            `json:"name" yaml:"name"`
+	UserID      string            `json:"user_id" yaml:"user_id"`
+	UserName    string            `json:"user_name" yaml:"user_name"`
+	ProjectID   string            `json:"project_id" yaml:"project_id"`
+	ProjectName string            `json:"project_name" yaml:"project_name"`
+	Flavor      NovaFlavor        `json:"flavor" yaml:"flavor"`
+	ImageID     string            `json:"image_id" yaml:"image_id"`
+	Package     string            `json:"package" yaml:"package"`
+	Extra       map[string]string `json:"extra,omitempty" yaml:"extra,omitempty"`
+}
+
+func novaInt(key, value string) int {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Debugf("Invalid value for %s in Nova annotation: %s", key, value)
+	}
+	return i
+}
+
+// ParseNovaAnnotation parses the annotation written by the Nova VMware
+// driver, one "key:value" per line, e.g.:
+//
+//	name:web-01
+//	userid:3f2a...
+//	flavor:name:m1.small
+//	flavor:memory_mb:2048
+//
+// It returns nil if the annotation has none of the Nova keys.
+func ParseNovaAnnotation(annotation string) *NovaAnnotation {
+	nova := NovaAnnotation{}
+	found := false
+	for _, line := range strings.Split(annotation, "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, value := kv[0], strings.TrimSpace(kv[1])
+		known := true
+		switch key {
+		case "name":
+			nova.Name = value
+		case "userid":
+			nova.UserID = value
+		case "username":
+			nova.UserName = value
+		case "projectid":
+			nova.ProjectID = value
+		case "projectname":
+			nova.ProjectName = value
+		case "imageid":
+			nova.ImageID = value
+		case "package":
+			nova.Package = value
+		case "flavor":
+			fkv := strings.SplitN(value, ":", 2)
+			if len(fkv) != 2 {
+				known = false
+				break
+			}
+			fkey, fvalue := fkv[0], strings.TrimSpace(fkv[1])
+			switch fkey {
+			case "name":
+				nova.Flavor.Name = fvalue
+			case "memory_mb":
+				nova.Flavor.MemoryMB = novaInt("flavor:"+fkey, fvalue)
+			case "vcpus":
+				nova.Flavor.VCPUs = novaInt("flavor:"+fkey, fvalue)
+			case "root_gb":
+				nova.Flavor.RootGB = novaInt("flavor:"+fkey, fvalue)
+			case "ephemeral_gb":
+				nova.Flavor.EphemeralGB = novaInt("flavor:"+fkey, fvalue)
+			case "swap":
+				nova.Flavor.SwapMB = novaInt("flavor:"+fkey, fvalue)
+			default:
+				key, value, known = "flavor:"+fkey, fvalue, false
+			}
+		default:
+			known = false
+		}
+		if known {
+			found = true
+		} else {
+			if nova.Extra == nil {
+				nova.Extra = make(map[string]string)
+			}
+			nova.Extra[key] = value
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &nova
+}
+
+func (nova *NovaAnnotation) writeTable(tw io.Writer) {
+	fmt.Fprintf(tw, "\tNova:\n")
+	fmt.Fprintf(tw, "\t\tName:\t%s\n", nova.Name)
+	fmt.Fprintf(tw, "\t\tUser:\t%s (%s)\n", nova.UserName, nova.UserID)
+	fmt.Fprintf(tw, "\t\tProject:\t%s (%s)\n", nova.ProjectName, nova.ProjectID)
+	fmt.Fprintf(tw, "\t\tFlavor:\t%s\n", nova.Flavor.Name)
+	fmt.Fprintf(tw, "\t\t\tMemory:\t%d MB\n", nova.Flavor.MemoryMB)
+	fmt.Fprintf(tw, "\t\t\tVCPUs:\t%d\n", nova.Flavor.VCPUs)
+	fmt.Fprintf(tw, "\t\t\tRootDisk:\t%d GB\n", nova.Flavor.RootGB)
+	fmt.Fprintf(tw, "\t\t\tEphemeralDisk:\t%d GB\n", nova.Flavor.EphemeralGB)
+	fmt.Fprintf(tw, "\t\t\tSwap:\t%d MB\n", nova.Flavor.SwapMB)
+	fmt.Fprintf(tw, "\t\tImageId:\t%s\n", nova.ImageID)
+	fmt.Fprintf(tw, "\t\tPackage:\t%s\n", nova.Package)
+	keys := []string{}
+	for k := range nova.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "\t\t%s:\t%s\n", k, nova.Extra[k])
+	}
+}
+
+// NovaFilter selects the VMs by their Nova annotation. The empty fields
+// match any value, the project and user are matched by name or ID.
+type NovaFilter struct {
+	Project string
+	User    string
+	Flavor  string
+	Image   string
+}
+
+// IsEmpty returns true if the filter has no conditions
+func (f *NovaFilter) IsEmpty() bool {
+	return f.Project == "" && f.User == "" && f.Flavor == "" && f.Image == ""
+}
+
+// Match returns true if the annotation matches all the conditions of the
+// filter. VMs without Nova annotation only match an empty filter.
+func (f *NovaFilter) Match(nova *NovaAnnotation) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	if nova == nil {
+		return false
+	}
+	equal := func(value string, candidates ...string) bool {
+		if value == "" {
+			return true
+		}
+		for _, c := range candidates {
+			if strings.EqualFold(value, c) {
+				return true
+			}
+		}
+		return false
+	}
+	return equal(f.Project, nova.ProjectName, nova.ProjectID) &&
+		equal(f.User, nova.UserName, nova.UserID) &&
+		equal(f.Flavor, nova.Flavor.Name) &&
+		equal(f.Image, nova.ImageID)
+}
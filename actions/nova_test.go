@@ -0,0 +1,66 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// novaVM returns a VM managed by Nova in the project
+func novaVM(project string, cpus int32, memoryMB int32, power types.VirtualMachinePowerState) mo.VirtualMachine {
+	vm := mo.VirtualMachine{}
+	vm.Summary.Config.ManagedBy = &types.ManagedByInfo{ExtensionKey: NovaExtensionKey}
+	vm.Summary.Config.Annotation = "projectid:" + project + "-id\nprojectname:" + project
+	vm.Summary.Config.NumCpu = cpus
+	vm.Summary.Config.MemorySizeMB = memoryMB
+	vm.Summary.Runtime.PowerState = power
+	vm.Summary.Storage = &types.VirtualMachineStorageSummary{Committed: 10, Uncommitted: 5}
+	return vm
+}
+
+func TestParseNovaAnnotation(t *testing.T) {
+	annotation := "name:web server 01\nuserid:5a1c\nusername:jose\nprojectid:8e3b\nprojectname:web\n" +
+		"flavor:name:m1.small\nflavor:memory_mb:2048\nflavor:vcpus:1\nflavor:ephemeral_gb:0\n" +
+		"flavor:root_gb:20\nflavor:swap:0\nimageid:0f1e\npackage:2015.1.2\nflavor:gpus:1"
+	nova := ParseNovaAnnotation(annotation)
+	if nova == nil {
+		t.Fatal("Nova annotation not recognized")
+	}
+	if nova.Name != "web server 01" || nova.ProjectName != "web" || nova.ImageID != "0f1e" || nova.Package != "2015.1.2" {
+		t.Errorf("Unexpected Nova annotation: %+v", nova)
+	}
+	flavor := NovaFlavor{Name: "m1.small", MemoryMB: 2048, VCPUs: 1, RootGB: 20}
+	if nova.Flavor != flavor {
+		t.Errorf("Unexpected flavor: %+v", nova.Flavor)
+	}
+	if nova.Extra["flavor:gpus"] != "1" {
+		t.Errorf("Unknown keys not kept: %v", nova.Extra)
+	}
+	d := VMDetail{Annotation: annotation, Nova: nova}
+	if out := render(t, OutputTable, &d); strings.Count(out, "Flavor:") != 1 {
+		t.Errorf("Unexpected annotation output:\n%s", out)
+	}
+	// Free-form annotations must not break the output
+	for _, a := range []string{"", "Owner John", "Owner: John\nbackup daily"} {
+		if nova := ParseNovaAnnotation(a); nova != nil {
+			t.Errorf("Free-form annotation %q parsed as Nova: %+v", a, nova)
+		}
+		d := VMDetail{Annotation: a}
+		render(t, OutputTable, &d)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-playground/log"
+	"github.com/vmware/govmomi"
+	"golang.org/x/net/context"
+)
+
+// sessionCache keeps the cookie of the authenticated session of a user in
+// a vCenter, in a file only readable by the user, so the next runs do not
+// have to log in again
+type sessionCache struct {
+	file string
+	u    *url.URL
+}
+
+// sessionLocks has a mutex per session file, so the connections of the
+// same user and vCenter do not race to log in and save their sessions
+var sessionLocks = struct {
+	sync.Mutex
+	files map[string]*sync.Mutex
+}{files: make(map[string]*sync.Mutex)}
+
+// cachedSession is the content of the session files
+type cachedSession struct {
+	Host    string         `json:"host"`
+	User    string         `json:"user"`
+	Cookies []*http.Cookie `json:"cookies"`
+}
+
+// newSessionCache returns the cache of the session of the user of the URL,
+// in a file of the directory named by the hash of the user and vCenter
+func newSessionCache(dir string, u *url.URL) *sessionCache {
+	key := fmt.Sprintf("%s@%s", u.User.Username(), u.Host)
+	file := filepath.Join(dir, fmt.Sprintf("%x.json", sha1.Sum([]byte(key))))
+	return &sessionCache{file, u}
+}
+
+// lock waits until no other connection uses the session file, it returns
+// the function to unlock it
+func (s *sessionCache) lock() func() {
+	sessionLocks.Lock()
+	m, ok := sessionLocks.files[s.file]
+	if !ok {
+		m = &sync.Mutex{}
+		sessionLocks.files[s.file] = m
+	}
+	sessionLocks.Unlock()
+	m.Lock()
+	return m.Unlock
+}
+
+// restore sets the cookie of the cached session in the client. It returns
+// true if the session is still valid.
+func (s *sessionCache) restore(ctx context.Context, c *govmomi.Client) bool {
+	var cached cachedSession
+
+	data, err := ioutil.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debugf("Error reading session cache: %s", err)
+		}
+		return false
+	}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		log.Debugf("Invalid session cache %s: %s", s.file, err)
+		return false
+	}
+	c.Jar.SetCookies(s.u, cached.Cookies)
+	session, err := c.SessionManager.UserSession(ctx)
+	if err != nil || session == nil {
+		log.Debugf("Cached session of %s in %s expired", cached.User, cached.Host)
+		return false
+	}
+	log.Debugf("Using the cached session of %s in %s", session.UserName, s.u.Host)
+	return true
+}
+
+// save writes the cookies of the client in the cache, replacing the file
+// at once so other processes never read half of it
+func (s *sessionCache) save(c *govmomi.Client) error {
+	cached := cachedSession{
+		Host:    s.u.Host,
+		User:    s.u.User.Username(),
+		Cookies: c.Jar.Cookies(s.u),
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.file)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// TempFile creates the file with mode 0600
+	f, err := ioutil.TempFile(dir, ".session")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.file)
+}
+
+// remove deletes the session from the cache
+func (s *sessionCache) remove() error {
+	if err := os.Remove(s.file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Logout closes the session of the user of the URL saved in the session
+// cache of the connection options, and removes it from the cache. It
+// returns false if there was no valid session to close.
+func Logout(u *url.URL, insecure bool, o ConnectOptions, ctx context.Context) (bool, error) {
+	if o.SessionDir == "" || u.User == nil {
+		return false, nil
+	}
+	cache := newSessionCache(o.SessionDir, u)
+	defer cache.lock()()
+	c, err := dial(ctx, u, insecure, o)
+	if err != nil {
+		return false, newError(ErrConnection, err, "Cannot connect with %s", u.Host)
+	}
+	found := cache.restore(ctx, c)
+	if found {
+		if err := c.Logout(ctx); err != nil {
+			return false, wrapError(err, "Error closing the session in %s", u.Host)
+		}
+	}
+	if err := cache.remove(); err != nil {
+		return found, fmt.Errorf("Error removing the session cache: %s", err)
+	}
+	return found, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestSessionCache(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "wminfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	o := ConnectOptions{SessionDir: dir}
+	ctx := context.Background()
+	connect(t, u, o).Close()
+	cache := newSessionCache(dir, u)
+	st, err := os.Stat(cache.file)
+	if err != nil {
+		t.Fatalf("Session not cached: %s", err)
+	}
+	if st.Mode().Perm() != 0600 {
+		t.Errorf("Session cache readable by others: %s", st.Mode())
+	}
+	// The next run reuses the session
+	c, err := dial(ctx, u, true, o)
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	if !cache.restore(ctx, c) {
+		t.Error("Cached session not restored")
+	}
+	if found, err := Logout(u, true, o, ctx); !found || err != nil {
+		t.Errorf("Error closing the cached session: %t, %v", found, err)
+	}
+	if _, err := os.Stat(cache.file); !os.IsNotExist(err) {
+		t.Errorf("Session cache not removed: %v", err)
+	}
+	if found, err := Logout(u, true, o, ctx); found || err != nil {
+		t.Errorf("Unexpected logout without cached session: %t, %v", found, err)
+	}
+	// Concurrent connections of the same user share the session
+	var wg sync.WaitGroup
+	keys := make([]string, 2)
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := Connect(u, true, o, ctx)
+			if err != nil {
+				t.Errorf("Error connecting: %s", err)
+				return
+			}
+			defer c.Close()
+			if session, err := c.client.SessionManager.UserSession(ctx); err == nil && session != nil {
+				keys[i] = session.Key
+			}
+		}(i)
+	}
+	wg.Wait()
+	if keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("Expected one session for both connections, got %v", keys)
+	}
+}
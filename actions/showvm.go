@@ -15,13 +15,18 @@ package actions
 
 import (
 	"fmt"
-	"net/url"
+	"io"
+	"net"
 	"os"
+	"path"
+	"regexp"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/go-playground/log"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/units"
 	"github.com/vmware/govmomi/vim25/mo"
@@ -29,33 +34,293 @@ import (
 	"golang.org/x/net/context"
 )
 
+// EntityRef is a reference to a related managed entity
+type EntityRef struct {
+	Reference string `json:"reference" yaml:"reference"`
+	Name      string `json:"name" yaml:"name"`
+}
+
+// VMConfig is the configuration summary of a VM
+type VMConfig struct {
+	Path                string `json:"path" yaml:"path"`
+	UUID                string `json:"uuid" yaml:"uuid"`
+	GuestFullName       string `json:"guest_full_name" yaml:"guest_full_name"`
+	MemoryMB            int32  `json:"memory_mb" yaml:"memory_mb"`
+	MemoryReservationMB int32  `json:"memory_reservation_mb" yaml:"memory_reservation_mb"`
+	NumCPU              int32  `json:"num_cpu" yaml:"num_cpu"`
+	CPUReservation      int32  `json:"cpu_reservation" yaml:"cpu_reservation"`
+	GuestID             string `json:"guest_id" yaml:"guest_id"`
+	InstanceUUID        string `json:"instance_uuid" yaml:"instance_uuid"`
+	EthernetCards       int32  `json:"ethernet_cards" yaml:"ethernet_cards"`
+	VirtualDisks        int32  `json:"virtual_disks" yaml:"virtual_disks"`
+	Template            bool   `json:"template" yaml:"template"`
+	ManagedBy           string `json:"managed_by,omitempty" yaml:"managed_by,omitempty"`
+}
+
+// VMGuest is the guest information reported by VMware Tools
+type VMGuest struct {
+	HostName           string         `json:"hostname" yaml:"hostname"`
+	IPAddress          string         `json:"ip_address" yaml:"ip_address"`
+	GuestID            string         `json:"guest_id" yaml:"guest_id"`
+	GuestFullName      string         `json:"guest_full_name" yaml:"guest_full_name"`
+	ToolsRunningStatus string         `json:"tools_running_status" yaml:"tools_running_status"`
+	ToolsVersionStatus string         `json:"tools_version_status" yaml:"tools_version_status"`
+	Nics               []VMGuestNic   `json:"nics" yaml:"nics"`
+	Routes             []VMGuestRoute `json:"routes" yaml:"routes"`
+	DNS                *VMGuestDNS    `json:"dns,omitempty" yaml:"dns,omitempty"`
+}
+
+// VMRuntime is the runtime environment of a VM
+type VMRuntime struct {
+	Host             string      `json:"host,omitempty" yaml:"host,omitempty"`
+	HostID           string      `json:"host_id,omitempty" yaml:"host_id,omitempty"`
+	BootTime         *time.Time  `json:"boot_time,omitempty" yaml:"boot_time,omitempty"`
+	PowerState       string      `json:"power_state" yaml:"power_state"`
+	Paused           bool        `json:"paused" yaml:"paused"`
+	CleanPowerOff    bool        `json:"clean_power_off" yaml:"clean_power_off"`
+	SuspendTime      *time.Time  `json:"suspend_time,omitempty" yaml:"suspend_time,omitempty"`
+	MemoryOverhead   int64       `json:"memory_overhead" yaml:"memory_overhead"`
+	MaxMemoryUsageMB int32       `json:"max_memory_usage_mb" yaml:"max_memory_usage_mb"`
+	MaxCPUUsage      int32       `json:"max_cpu_usage" yaml:"max_cpu_usage"`
+	Networks         []EntityRef `json:"networks" yaml:"networks"`
+	Portgroups       []EntityRef `json:"portgroups" yaml:"portgroups"`
+}
+
+// VMStorage is the storage usage of a VM, in bytes
+type VMStorage struct {
+	Uncommitted int64       `json:"uncommitted" yaml:"uncommitted"`
+	Committed   int64       `json:"committed" yaml:"committed"`
+	Unshared    int64       `json:"unshared" yaml:"unshared"`
+	Datastores  []EntityRef `json:"datastores" yaml:"datastores"`
+}
+
+// VMQuickStats are the performance counters of a VM
+type VMQuickStats struct {
+	OverallCPUDemand         int32 `json:"overall_cpu_demand" yaml:"overall_cpu_demand"`
+	OverallCPUUsage          int32 `json:"overall_cpu_usage" yaml:"overall_cpu_usage"`
+	BalloonedMemoryMB        int32 `json:"ballooned_memory_mb" yaml:"ballooned_memory_mb"`
+	CompressedMemoryMB       int64 `json:"compressed_memory_mb" yaml:"compressed_memory_mb"`
+	ConsumedOverheadMemoryMB int32 `json:"consumed_overhead_memory_mb" yaml:"consumed_overhead_memory_mb"`
+	GuestMemoryUsageMB       int32 `json:"guest_memory_usage_mb" yaml:"guest_memory_usage_mb"`
+	HostMemoryUsageMB        int32 `json:"host_memory_usage_mb" yaml:"host_memory_usage_mb"`
+	SwappedMemoryMB          int32 `json:"swapped_memory_mb" yaml:"swapped_memory_mb"`
+	SharedMemoryMB           int32 `json:"shared_memory_mb" yaml:"shared_memory_mb"`
+	PrivateMemoryMB          int32 `json:"private_memory_mb" yaml:"private_memory_mb"`
+	UptimeSeconds            int32 `json:"uptime_seconds" yaml:"uptime_seconds"`
+}
+
+// VMDetail is the full description of a VM given by the show action
+type VMDetail struct {
+	Reference  string          `json:"reference" yaml:"reference"`
+	Name       string          `json:"name" yaml:"name"`
+	Config     VMConfig        `json:"config" yaml:"config"`
+	Guest      VMGuest         `json:"guest" yaml:"guest"`
+	Runtime    VMRuntime       `json:"runtime" yaml:"runtime"`
+	Storage    VMStorage       `json:"storage" yaml:"storage"`
+	QuickStats VMQuickStats    `json:"quickstats" yaml:"quickstats"`
+	Devices    VMDevices       `json:"devices" yaml:"devices"`
+	Snapshots  []VMSnapshot    `json:"snapshots" yaml:"snapshots"`
+	Annotation string          `json:"annotation" yaml:"annotation"`
+	Nova       *NovaAnnotation `json:"nova,omitempty" yaml:"nova,omitempty"`
+	Console    string          `json:"console,omitempty" yaml:"console,omitempty"`
+}
+
 // ShowVM represents a class to show VM properties
 type ShowVM struct {
 	*ListVMs
-	vms []mo.VirtualMachine
-	pc  *property.Collector
+	search   []string
+	found    []types.ManagedObjectReference
+	matchers []func(string) bool
+	regex    bool
+	limit    int
+	vms      []mo.VirtualMachine
+	pc       *property.Collector
+
+	consoleOptions ConsoleOptions
 }
 
 // NewShowVM is the constructor
-func NewShowVM(u *url.URL, insecure bool, dc string, ctx context.Context) *ShowVM {
-	showvm := ShowVM{}
-	showvm.ListVMs = NewListVMs(u, insecure, dc, ctx)
-	log.Debug("ListNets constructor")
-	return &showvm
+func NewShowVM(c *Connection, dc string, ctx context.Context) (*ShowVM, error) {
+	showvm := ShowVM{limit: DefaultShowLimit, consoleOptions: DefaultConsoleOptions()}
+	l, err := NewListVMs(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	showvm.ListVMs = l
+	log.Debug("ShowVM constructor")
+	return &showvm, nil
+}
+
+// DefaultShowLimit is the maximum number of VMs shown by default
+const DefaultShowLimit = 10
+
+// SetRegex makes the search parameters regular expressions instead of
+// globs
+func (showvm *ShowVM) SetRegex(regex bool) {
+	showvm.regex = regex
+}
+
+// SetLimit sets the maximum number of VMs to show, 0 means no limit
+func (showvm *ShowVM) SetLimit(limit int) {
+	showvm.limit = limit
+}
+
+// isGlob returns true if the search parameter has wildcards
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// newMatcher returns a function which matches the values with the search
+// parameter, ignoring the case: a regular expression if regex is true, a
+// glob if it has wildcards or an exact match
+func newMatcher(s string, regex bool) (func(string) bool, error) {
+	switch {
+	case regex:
+		re, err := regexp.Compile("(?i)" + s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regular expression '%s': %s", s, err)
+		}
+		return re.MatchString, nil
+	case isGlob(s):
+		pattern := strings.ToLower(s)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid pattern '%s': %s", s, err)
+		}
+		return func(value string) bool {
+			ok, _ := path.Match(pattern, strings.ToLower(value))
+			return ok
+		}, nil
+	}
+	return func(value string) bool {
+		return strings.EqualFold(value, s)
+	}, nil
+}
+
+// uuidRegexp matches the BIOS and instance UUIDs of the VMs
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+
+// lookupKind returns the kind of indexed lookup for the search parameter
+// (uuid, ip, path or dns) and the value to look for. The prefixes uuid:,
+// ip:, dns: and path: force the kind, forced is false if it was guessed.
+func lookupKind(s string) (kind string, value string, forced bool) {
+	if i := strings.Index(s, ":"); i > 0 {
+		switch prefix := strings.ToLower(s[:i]); prefix {
+		case "uuid", "ip", "dns", "path":
+			return prefix, s[i+1:], true
+		}
+	}
+	switch {
+	case net.ParseIP(s) != nil:
+		return "ip", s, false
+	case uuidRegexp.MatchString(s):
+		return "uuid", s, false
+	case strings.HasPrefix(s, "/"):
+		return "path", s, false
+	case strings.Contains(s, "."):
+		return "dns", s, false
+	}
+	return "", s, false
+}
+
+// lookup finds the VM with the SearchIndex of VCenter, it returns nil if
+// the VM was not found or the kind of search parameter is not indexed
+func (showvm *ShowVM) lookup(index *object.SearchIndex, dc *object.Datacenter, kind string, value string) (*types.ManagedObjectReference, error) {
+	var ref object.Reference
+	var err error
+
+	log.Debugf("Looking up VM by %s: %s", kind, value)
+	switch kind {
+	case "ip":
+		ref, err = index.FindByIp(showvm.ctx, dc, value, true)
+	case "dns":
+		ref, err = index.FindByDnsName(showvm.ctx, dc, value, true)
+	case "uuid":
+		// BIOS UUID first, then the instance UUID (e.g. OpenStack)
+		if ref, err = index.FindByUuid(showvm.ctx, dc, value, true, nil); err == nil && ref == nil {
+			ref, err = index.FindByUuid(showvm.ctx, dc, value, true, types.NewBool(true))
+		}
+	case "path":
+		// Relative paths are taken from the VM folder of the datacenter
+		if !strings.HasPrefix(value, "/") {
+			value = path.Join(dc.InventoryPath, "vm", value)
+		}
+		ref, err = index.FindByInventoryPath(showvm.ctx, value)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, wrapError(err, "Error looking up VM by %s '%s'", kind, value)
+	}
+	if ref == nil || ref.Reference().Type != "VirtualMachine" {
+		return nil, nil
+	}
+	mor := ref.Reference()
+	return &mor, nil
 }
 
-func (showvm *ShowVM) console(vm *mo.VirtualMachine) string {
-	port := "7331"
-	consoleURL := "http://%s:%s/console/?vmId=%s&vmName=%s&host=%s&sessionTicket=%s&thumbprint=%s"
-	sessionTicket := showvm.clonesession()
-	thumbprint := showvm.fingerprint()
-	host := showvm.host()
-	vcenter := showvm.url.Host
-	vmID := vm.Reference().Value
-	return fmt.Sprintf(consoleURL, vcenter, port, vmID, vm.Name, host, sessionTicket, thumbprint)
+// Search looks up the VMs by IP, DNS name, UUID or inventory path with
+// the SearchIndex of VCenter. The search parameters not found (or not
+// indexed, like the names) are matched by Collect with all the VMs of
+// the datacenter, unless the kind of lookup was forced with a prefix
+// (uuid:, ip:, dns: or path:), then the lookup errors are returned. The
+// index only returns the first VM with an IP or DNS name, so they are
+// also matched by Collect when the kind was guessed. The globs and
+// regular expressions (see SetRegex) are always matched by Collect.
+// It will return the number of references found.
+func (showvm *ShowVM) Search(s ...string) (int, error) {
+	var pending []string
+
+	if len(s) == 0 {
+		s = []string{"*"}
+	}
+	showvm.search = append(showvm.search, s...)
+	finder := find.NewFinder(showvm.client.Client, true)
+	dc, err := finder.DatacenterOrDefault(showvm.ctx, showvm.dc)
+	if err != nil {
+		return 0, datacenterError(err, showvm.dc)
+	}
+	index := object.NewSearchIndex(showvm.client.Client)
+	for _, term := range s {
+		kind, value, forced := lookupKind(term)
+		// Patterns are not indexed
+		if !forced && (showvm.regex || isGlob(term)) {
+			kind = ""
+		}
+		ref, err := showvm.lookup(index, dc, kind, value)
+		if err != nil {
+			if forced {
+				return 0, err
+			}
+			log.Debugf("%s", err)
+		}
+		switch {
+		case ref != nil:
+			showvm.found = append(showvm.found, *ref)
+			if !forced && (kind == "ip" || kind == "dns") {
+				pending = append(pending, term)
+			}
+		case forced:
+			return 0, newError(ErrNotFound, nil, "No VM found with %s %s", kind, value)
+		default:
+			pending = append(pending, term)
+		}
+	}
+	if len(pending) == 0 {
+		return len(showvm.found), nil
+	}
+	for _, term := range pending {
+		m, err := newMatcher(term, showvm.regex)
+		if err != nil {
+			return 0, err
+		}
+		showvm.matchers = append(showvm.matchers, m)
+	}
+	log.Debugf("Not found in the index, scanning the VMs for: %s", strings.Join(pending, ", "))
+	n, err := showvm.ListVMs.Search(pending...)
+	return n + len(showvm.found), err
 }
 
-func (showvm *ShowVM) collectReferences(vm *mo.VirtualMachine) ([]mo.HostSystem, []mo.Network, []mo.DistributedVirtualPortgroup, []mo.Datastore) {
+func (showvm *ShowVM) collectReferences(ctx context.Context, vm *mo.VirtualMachine) ([]mo.HostSystem, []mo.Network, []mo.DistributedVirtualPortgroup, []mo.Datastore, error) {
 	var host []mo.HostSystem
 	var network []mo.Network
 	var dvp []mo.DistributedVirtualPortgroup
@@ -120,153 +385,340 @@ func (showvm *ShowVM) collectReferences(vm *mo.VirtualMachine) ([]mo.HostSystem,
 	// Process the references
 	for _, vref := range vrefs {
 		if vref.refs != nil {
-			if err := showvm.pc.Retrieve(showvm.ctx, vref.refs, []string{"name"}, vref.dest); err != nil {
-				log.Panicf("Error retrieving resources references: %s", err)
+			if err := showvm.pc.Retrieve(ctx, vref.refs, []string{"name"}, vref.dest); err != nil {
+				return nil, nil, nil, nil, wrapError(err, "Error retrieving resources references")
 			}
 			vref.save()
 		}
 	}
-	return host, network, dvp, datastore
+	return host, network, dvp, datastore, nil
 }
 
-// Print dumps a table with the results
-func (showvm *ShowVM) Print(p ...string) {
+func (showvm *ShowVM) detail(ctx context.Context, vm *mo.VirtualMachine) (VMDetail, error) {
+	host, network, dvp, datastore, err := showvm.collectReferences(ctx, vm)
+	if err != nil {
+		return VMDetail{}, err
+	}
+	d := VMDetail{
+		Reference: vm.Reference().Value,
+		Name:      vm.Name,
+		Config: VMConfig{
+			Path:                vm.Summary.Config.VmPathName,
+			UUID:                vm.Summary.Config.Uuid,
+			GuestFullName:       vm.Summary.Config.GuestFullName,
+			MemoryMB:            vm.Summary.Config.MemorySizeMB,
+			MemoryReservationMB: vm.Summary.Config.MemoryReservation,
+			NumCPU:              vm.Summary.Config.NumCpu,
+			CPUReservation:      vm.Summary.Config.CpuReservation,
+			GuestID:             vm.Summary.Config.GuestId,
+			InstanceUUID:        vm.Summary.Config.InstanceUuid,
+			EthernetCards:       vm.Summary.Config.NumEthernetCards,
+			VirtualDisks:        vm.Summary.Config.NumVirtualDisks,
+			Template:            vm.Summary.Config.Template,
+		},
+		Runtime: VMRuntime{
+			BootTime:         vm.Summary.Runtime.BootTime,
+			PowerState:       string(vm.Summary.Runtime.PowerState),
+			MemoryOverhead:   vm.Summary.Runtime.MemoryOverhead,
+			MaxMemoryUsageMB: vm.Summary.Runtime.MaxMemoryUsage,
+			MaxCPUUsage:      vm.Summary.Runtime.MaxCpuUsage,
+			Networks:         []EntityRef{},
+			Portgroups:       []EntityRef{},
+		},
+		Storage: VMStorage{
+			Datastores: []EntityRef{},
+		},
+		QuickStats: VMQuickStats{
+			OverallCPUDemand:         vm.Summary.QuickStats.OverallCpuDemand,
+			OverallCPUUsage:          vm.Summary.QuickStats.OverallCpuUsage,
+			BalloonedMemoryMB:        vm.Summary.QuickStats.BalloonedMemory,
+			CompressedMemoryMB:       vm.Summary.QuickStats.CompressedMemory,
+			ConsumedOverheadMemoryMB: vm.Summary.QuickStats.ConsumedOverheadMemory,
+			GuestMemoryUsageMB:       vm.Summary.QuickStats.GuestMemoryUsage,
+			HostMemoryUsageMB:        vm.Summary.QuickStats.HostMemoryUsage,
+			SwappedMemoryMB:          vm.Summary.QuickStats.SwappedMemory,
+			SharedMemoryMB:           vm.Summary.QuickStats.SharedMemory,
+			PrivateMemoryMB:          vm.Summary.QuickStats.PrivateMemory,
+			UptimeSeconds:            vm.Summary.QuickStats.UptimeSeconds,
+		},
+		Annotation: vm.Summary.Config.Annotation,
+		Nova:       ParseNovaAnnotation(vm.Summary.Config.Annotation),
+	}
+	if vm.Summary.Config.ManagedBy != nil {
+		d.Config.ManagedBy = vm.Summary.Config.ManagedBy.ExtensionKey
+	}
+	if g := vm.Summary.Guest; g != nil {
+		d.Guest = VMGuest{
+			HostName:           g.HostName,
+			IPAddress:          g.IpAddress,
+			GuestID:            g.GuestId,
+			GuestFullName:      g.GuestFullName,
+			ToolsRunningStatus: g.ToolsRunningStatus,
+			ToolsVersionStatus: g.ToolsVersionStatus,
+		}
+	}
+	d.Guest.Nics, d.Guest.Routes, d.Guest.DNS = guestNetwork(vm)
+	if len(host) > 0 {
+		d.Runtime.Host = host[0].Name
+		d.Runtime.HostID = vm.Summary.Runtime.Host.Value
+	}
+	if vm.Summary.Runtime.PowerState != "poweredOn" {
+		if vm.Summary.Runtime.Paused != nil {
+			d.Runtime.Paused = *vm.Summary.Runtime.Paused
+		}
+		if vm.Summary.Runtime.CleanPowerOff != nil {
+			d.Runtime.CleanPowerOff = *vm.Summary.Runtime.CleanPowerOff
+		}
+		d.Runtime.SuspendTime = vm.Summary.Runtime.SuspendTime
+	}
+	for _, i := range network {
+		d.Runtime.Networks = append(d.Runtime.Networks, EntityRef{i.Reference().Value, i.Name})
+	}
+	for _, i := range dvp {
+		d.Runtime.Portgroups = append(d.Runtime.Portgroups, EntityRef{i.Reference().Value, i.Name})
+	}
+	if st := vm.Summary.Storage; st != nil {
+		d.Storage.Uncommitted = st.Uncommitted
+		d.Storage.Committed = st.Committed
+		d.Storage.Unshared = st.Unshared
+	}
+	datastores := make(map[string]string)
+	for _, i := range datastore {
+		d.Storage.Datastores = append(d.Storage.Datastores, EntityRef{i.Reference().Value, i.Name})
+		datastores[i.Reference().Value] = i.Name
+	}
+	portgroups := make(map[string]string)
+	for _, i := range dvp {
+		portgroups[i.Reference().Value] = i.Name
+	}
+	d.Devices = vmDevices(vm, datastores, portgroups)
+	d.Snapshots = vmSnapshots(vm)
+	if showvm.consoleOptions.Enabled {
+		if d.Console, err = showvm.console(vm); err != nil {
+			// The details are still useful without the console
+			log.Errorf("%s", err)
+		}
+	}
+	return d, nil
+}
+
+// WriteTable renders the VM details as sections of key/value pairs
+func (d *VMDetail) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "VM config\n")
+	fmt.Fprintf(tw, "\tName:\t%s\n", d.Name)
+	fmt.Fprintf(tw, "\tId:\t%s\n", d.Reference)
+	fmt.Fprintf(tw, "\tPath:\t%s\n", d.Config.Path)
+	fmt.Fprintf(tw, "\tUUID:\t%s\n", d.Config.UUID)
+	fmt.Fprintf(tw, "\tGuest: \t%s\n", d.Config.GuestFullName)
+	fmt.Fprintf(tw, "\tMemory:\t%d MB\n", d.Config.MemoryMB)
+	fmt.Fprintf(tw, "\tMemoryReservation:\t%d MB\n", d.Config.MemoryReservationMB)
+	fmt.Fprintf(tw, "\tCPU:\t%d vCPU(s)\n", d.Config.NumCPU)
+	fmt.Fprintf(tw, "\tCpuReservation:\t%d\n", d.Config.CPUReservation)
+	fmt.Fprintf(tw, "\tGuestId:\t%s\n", d.Config.GuestID)
+	fmt.Fprintf(tw, "\tInstanceUuid:\t%s\n", d.Config.InstanceUUID)
+	fmt.Fprintf(tw, "\tEthernetCards:\t%d\n", d.Config.EthernetCards)
+	fmt.Fprintf(tw, "\tVirtualDisks:\t%d\n", d.Config.VirtualDisks)
+	fmt.Fprintf(tw, "\tTemplate:\t%t\n", d.Config.Template)
+	if d.Config.ManagedBy != "" {
+		fmt.Fprintf(tw, "\tManagedBy:\t%s\n", d.Config.ManagedBy)
+	}
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Guest\n")
+	fmt.Fprintf(tw, "\tHostName: \t%s\n", d.Guest.HostName)
+	fmt.Fprintf(tw, "\tIpAddress: \t%s\n", d.Guest.IPAddress)
+	fmt.Fprintf(tw, "\tGuestId: \t%s\n", d.Guest.GuestID)
+	fmt.Fprintf(tw, "\tGuestFullName: \t%s\n", d.Guest.GuestFullName)
+	fmt.Fprintf(tw, "\tToolsRunningStatus: \t%s\n", d.Guest.ToolsRunningStatus)
+	fmt.Fprintf(tw, "\tToolsVersionStatus: \t%s\n", d.Guest.ToolsVersionStatus)
+	d.Guest.writeNetwork(tw)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Runtime env\n")
+	if d.Runtime.HostID != "" {
+		fmt.Fprintf(tw, "\tHost:\t%s\n", d.Runtime.Host)
+		fmt.Fprintf(tw, "\tHostId:\t%s\n", d.Runtime.HostID)
+	}
+	if d.Runtime.BootTime != nil {
+		fmt.Fprintf(tw, "\tBootTime:\t%s\n", d.Runtime.BootTime)
+	}
+	fmt.Fprintf(tw, "\tPowerState: \t%s\n", d.Runtime.PowerState)
+	if d.Runtime.PowerState != "poweredOn" {
+		fmt.Fprintf(tw, "\tPaused:\t%t\n", d.Runtime.Paused)
+		fmt.Fprintf(tw, "\tCleanPowerOff:\t%t\n", d.Runtime.CleanPowerOff)
+		fmt.Fprintf(tw, "\tSuspendTime:\t%s\n", d.Runtime.SuspendTime)
+	}
+	fmt.Fprintf(tw, "\tMemoryOverhead:\t%d MB\n", d.Runtime.MemoryOverhead)
+	fmt.Fprintf(tw, "\tMaxMemoryUsage:\t%d MB\n", d.Runtime.MaxMemoryUsageMB)
+	fmt.Fprintf(tw, "\tMaxCpuUsage:\t%d\n", d.Runtime.MaxCPUUsage)
+	if len(d.Runtime.Networks) > 0 {
+		fmt.Fprintf(tw, "\tNetwork(s):\n")
+		for _, i := range d.Runtime.Networks {
+			fmt.Fprintf(tw, "\t\t%s: %s\n", i.Reference, i.Name)
+		}
+	}
+	if len(d.Runtime.Portgroups) > 0 {
+		fmt.Fprintf(tw, "\tVirtual Switch(s):\n")
+		for _, i := range d.Runtime.Portgroups {
+			fmt.Fprintf(tw, "\t\t%s: %s\n", i.Reference, i.Name)
+		}
+	}
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Storage\n")
+	fmt.Fprintf(tw, "\tUncommitted:\t%s\n", units.ByteSize(d.Storage.Uncommitted))
+	fmt.Fprintf(tw, "\tCommitted:\t%s\n", units.ByteSize(d.Storage.Committed))
+	fmt.Fprintf(tw, "\tUnshared:\t%s\n", units.ByteSize(d.Storage.Unshared))
+	fmt.Fprintf(tw, "\tDatastores:\n")
+	for _, i := range d.Storage.Datastores {
+		fmt.Fprintf(tw, "\t\t%s: %s\n", i.Reference, i.Name)
+	}
+	fmt.Fprintf(tw, "\n")
+	d.Devices.writeTable(tw)
+	fmt.Fprintf(tw, "\n")
+	if len(d.Snapshots) > 0 {
+		fmt.Fprintf(tw, "Snapshots\n")
+		writeSnapshots(tw, d.Snapshots, "\t")
+		fmt.Fprintf(tw, "\n")
+	}
+	fmt.Fprintf(tw, "QuickStats\n")
+	fmt.Fprintf(tw, "\tOverallCpuDemand:\t%d\n", d.QuickStats.OverallCPUDemand)
+	fmt.Fprintf(tw, "\tOverallCpuUsage:\t%d\n", d.QuickStats.OverallCPUUsage)
+	fmt.Fprintf(tw, "\tBalloonedMemory:\t%d MB\n", d.QuickStats.BalloonedMemoryMB)
+	fmt.Fprintf(tw, "\tCompressedMemory:\t%d MB\n", d.QuickStats.CompressedMemoryMB)
+	fmt.Fprintf(tw, "\tConsumedOverheadMemory:\t%dMB\n", d.QuickStats.ConsumedOverheadMemoryMB)
+	fmt.Fprintf(tw, "\tGuestMemoryUsage:\t%d MB\n", d.QuickStats.GuestMemoryUsageMB)
+	fmt.Fprintf(tw, "\tHostMemoryUsage:\t%d MB\n", d.QuickStats.HostMemoryUsageMB)
+	fmt.Fprintf(tw, "\tSwappedMemory:\t%d MB\n", d.QuickStats.SwappedMemoryMB)
+	fmt.Fprintf(tw, "\tSharedMemory:\t%d MB\n", d.QuickStats.SharedMemoryMB)
+	fmt.Fprintf(tw, "\tPrivateMemory:\t%d MB\n", d.QuickStats.PrivateMemoryMB)
+	fmt.Fprintf(tw, "\tUptimeSeconds:\t%d s\n", d.QuickStats.UptimeSeconds)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Annotations\n")
+	if d.Nova != nil {
+		d.Nova.writeTable(tw)
+	} else if d.Annotation != "" {
+		for _, line := range strings.Split(d.Annotation, "\n") {
+			fmt.Fprintf(tw, "\t%s\n", line)
+		}
+	}
+	if d.Console != "" {
+		fmt.Fprintf(tw, "\n")
+		fmt.Fprintf(tw, "Console\n")
+		fmt.Fprintf(tw, "\t%s\n", d.Console)
+	}
+	return tw.Flush()
+}
+
+// VMDetailList is the data collected by the show action
+type VMDetailList []VMDetail
+
+// WriteTable renders the details of all VMs
+func (l VMDetailList) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "VirtualMachine(s): %d\n", len(l))
+	fmt.Fprintf(w, "---------------------\n")
+	for i := range l {
+		if err := l[i].WriteTable(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vmMatchProperties are the properties needed to match the VMs with the
+// search parameters of show
+var vmMatchProperties = []string{"name", "guest.ipAddress", "guest.hostName"}
+
+// match returns true if the reference, name, guest hostname or IP of the
+// VM match any of the search parameters
+func (showvm *ShowVM) match(vm *mo.VirtualMachine) bool {
+	values := []string{vm.Reference().Value, vm.Name}
+	if vm.Guest != nil {
+		values = append(values, vm.Guest.HostName, vm.Guest.IpAddress)
+	}
+	for _, m := range showvm.matchers {
+		for _, v := range values {
+			if v != "" && m(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// referenceValues returns the values of the references
+func referenceValues(refs []types.ManagedObjectReference) []string {
+	values := []string{}
+	for _, r := range refs {
+		values = append(values, r.Value)
+	}
+	return values
+}
+
+// Collect retrieves the properties of the VMs found by Search in the
+// index and the ones which match the rest of search parameters. Only the
+// properties needed to match are retrieved for all the VMs, the rest only
+// for the matched ones.
+// It returns a VMDetailList.
+func (showvm *ShowVM) Collect(ctx context.Context, p ...string) (Result, error) {
+	var candidates []mo.VirtualMachine
 	var vms []mo.VirtualMachine
-	var fvms []mo.VirtualMachine
-	var pvms *[]mo.VirtualMachine
 
 	if len(p) == 0 {
-		p = []string{"name", "summary", "guest", "config", "datastore", "network"}
+		p = []string{"name", "summary", "guest", "config", "datastore", "network", "snapshot"}
 	}
-	if pc, err := property.DefaultCollector(showvm.base.client.Client).Create(showvm.base.ctx); err == nil {
-		log.Debug("Printing information ...")
-		showvm.pc = pc
-		if err := pc.Retrieve(showvm.base.ctx, showvm.refs, p, &vms); err != nil {
-			log.Panicf("Error retrieving resources information from references: %s", err)
+	pc, err := showvm.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Collecting information ...")
+	showvm.pc = pc
+	refs := append([]types.ManagedObjectReference{}, showvm.found...)
+	if len(showvm.matchers) > 0 {
+		if err := showvm.retrieve(ctx, pc, vmMatchProperties, &candidates); err != nil {
+			return nil, err
 		}
-		// Filter the search here!
-		// TODO improve this!
-		if len(showvm.search) > 0 {
-			for _, vm := range vms {
-				for _, s := range showvm.search {
-					if vm.Reference().Value == s ||
-						strings.ToLower(vm.Name) == s ||
-						(vm.Summary.Guest != nil &&
-							(strings.ToLower(vm.Summary.Guest.HostName) == s ||
-								strings.ToLower(vm.Summary.Guest.IpAddress) == s)) {
-						fvms = append(fvms, vm)
-						break
-					}
-				}
+		for i := range candidates {
+			ref := candidates[i].Reference()
+			if showvm.match(&candidates[i]) && !contains(ref.Value, referenceValues(refs)) {
+				refs = append(refs, ref)
 			}
-			pvms = &fvms
-		} else {
-			pvms = &vms
 		}
-		tw := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
-		fmt.Fprintf(tw, "VirtualMachine(s): %d\n", len(*pvms))
-		fmt.Fprintf(tw, "---------------------\n")
-		for _, vm := range *pvms {
-			host, network, dvp, datastore := showvm.collectReferences(&vm)
-			console := showvm.console(&vm)
-			//fmt.Fprintf(tw, "VM:\t%s\n", vm)
-			fmt.Fprintf(tw, "VM config\n")
-			fmt.Fprintf(tw, "\tName:\t%s\n", vm.Name)
-			fmt.Fprintf(tw, "\tId:\t%s\n", vm.Reference().Value)
-			fmt.Fprintf(tw, "\tPath:\t%s\n", vm.Summary.Config.VmPathName)
-			fmt.Fprintf(tw, "\tUUID:\t%s\n", vm.Summary.Config.Uuid)
-			fmt.Fprintf(tw, "\tGuest: \t%s\n", vm.Summary.Config.GuestFullName)
-			fmt.Fprintf(tw, "\tMemory:\t%d MB\n", vm.Summary.Config.MemorySizeMB)
-			fmt.Fprintf(tw, "\tMemoryReservation:\t%d MB\n", vm.Summary.Config.MemoryReservation)
-			fmt.Fprintf(tw, "\tCPU:\t%d vCPU(s)\n", vm.Summary.Config.NumCpu)
-			fmt.Fprintf(tw, "\tCpuReservation:\t%d\n", vm.Summary.Config.CpuReservation)
-			fmt.Fprintf(tw, "\tGuestId:\t%s\n", vm.Summary.Config.GuestId)
-			fmt.Fprintf(tw, "\tInstanceUuid:\t%s\n", vm.Summary.Config.InstanceUuid)
-			fmt.Fprintf(tw, "\tEthernetCards:\t%d\n", vm.Summary.Config.NumEthernetCards)
-			fmt.Fprintf(tw, "\tVirtualDisks:\t%d\n", vm.Summary.Config.NumVirtualDisks)
-			fmt.Fprintf(tw, "\tTemplate:\t%t\n", vm.Summary.Config.Template)
-			if vm.Summary.Config.ManagedBy != nil {
-				fmt.Fprintf(tw, "\tManagedBy:\t%s\n", vm.Summary.Config.ManagedBy.ExtensionKey)
-			}
-			fmt.Fprintf(tw, "\n")
-			fmt.Fprintf(tw, "Guest\n")
-			fmt.Fprintf(tw, "\tHostName: \t%s\n", vm.Summary.Guest.HostName)
-			fmt.Fprintf(tw, "\tIpAddress: \t%s\n", vm.Summary.Guest.IpAddress)
-			fmt.Fprintf(tw, "\tGuestId: \t%s\n", vm.Summary.Guest.GuestId)
-			fmt.Fprintf(tw, "\tGuestFullName: \t%s\n", vm.Summary.Guest.GuestFullName)
-			fmt.Fprintf(tw, "\tToolsRunningStatus: \t%s\n", vm.Summary.Guest.ToolsRunningStatus)
-			fmt.Fprintf(tw, "\tToolsVersionStatus: \t%s\n", vm.Summary.Guest.ToolsVersionStatus)
-			fmt.Fprintf(tw, "\n")
-			fmt.Fprintf(tw, "Runtime env\n")
-			if len(host) > 0 {
-				fmt.Fprintf(tw, "\tHost:\t%s\n", host[0].Name)
-				fmt.Fprintf(tw, "\tHostId:\t%s\n", vm.Summary.Runtime.Host.Value)
-			}
-			if vm.Summary.Runtime.BootTime != nil {
-				fmt.Fprintf(tw, "\tBootTime:\t%s\n", vm.Summary.Runtime.BootTime)
-			}
-			fmt.Fprintf(tw, "\tPowerState: \t%s\n", vm.Summary.Runtime.PowerState)
-			if vm.Summary.Runtime.PowerState != "poweredOn" {
-				fmt.Fprintf(tw, "\tPaused:\t%t\n", vm.Summary.Runtime.Paused)
-				fmt.Fprintf(tw, "\tCleanPowerOff:\t%t\n", vm.Summary.Runtime.CleanPowerOff)
-				fmt.Fprintf(tw, "\tSuspendTime:\t%s\n", vm.Summary.Runtime.SuspendTime)
-			}
-			fmt.Fprintf(tw, "\tMemoryOverhead:\t%d MB\n", vm.Summary.Runtime.MemoryOverhead)
-			fmt.Fprintf(tw, "\tMaxMemoryUsage:\t%d MB\n", vm.Summary.Runtime.MaxMemoryUsage)
-			fmt.Fprintf(tw, "\tMaxCpuUsage:\t%d\n", vm.Summary.Runtime.MaxCpuUsage)
-			if len(network) > 0 {
-				fmt.Fprintf(tw, "\tNetwork(s):\n")
-				for _, i := range network {
-					fmt.Fprintf(tw, "\t\t%s: %s\n", i.Reference().Value, i.Name)
-				}
-			}
-			if len(dvp) > 0 {
-				fmt.Fprintf(tw, "\tVirtual Switch(s):\n")
-				for _, i := range dvp {
-					fmt.Fprintf(tw, "\t\t%s: %s\n", i.Reference().Value, i.Name)
-				}
-			}
-			fmt.Fprintf(tw, "\n")
-			fmt.Fprintf(tw, "Storage\n")
-			fmt.Fprintf(tw, "\tUncommitted:\t%s\n", units.ByteSize(vm.Summary.Storage.Uncommitted))
-			fmt.Fprintf(tw, "\tCommitted:\t%s\n", units.ByteSize(vm.Summary.Storage.Committed))
-			fmt.Fprintf(tw, "\tUnshared:\t%s\n", units.ByteSize(vm.Summary.Storage.Unshared))
-			fmt.Fprintf(tw, "\tDatastores:\n")
-			for _, i := range datastore {
-				fmt.Fprintf(tw, "\t\t%s: %s\n", i.Reference().Value, i.Name)
-			}
-			fmt.Fprintf(tw, "\n")
-			fmt.Fprintf(tw, "QuickStats\n")
-			fmt.Fprintf(tw, "\tOverallCpuDemand:\t%d\n", vm.Summary.QuickStats.OverallCpuDemand)
-			fmt.Fprintf(tw, "\tOverallCpuUsage:\t%d\n", vm.Summary.QuickStats.OverallCpuUsage)
-			fmt.Fprintf(tw, "\tBalloonedMemory:\t%d MB\n", vm.Summary.QuickStats.BalloonedMemory)
-			fmt.Fprintf(tw, "\tCompressedMemory:\t%d MB\n", vm.Summary.QuickStats.CompressedMemory)
-			fmt.Fprintf(tw, "\tConsumedOverheadMemory:\t%dMB\n", vm.Summary.QuickStats.ConsumedOverheadMemory)
-			fmt.Fprintf(tw, "\tGuestMemoryUsage:\t%d MB\n", vm.Summary.QuickStats.GuestMemoryUsage)
-			fmt.Fprintf(tw, "\tHostMemoryUsage:\t%d MB\n", vm.Summary.QuickStats.HostMemoryUsage)
-			fmt.Fprintf(tw, "\tSwappedMemory:\t%d MB\n", vm.Summary.QuickStats.SwappedMemory)
-			fmt.Fprintf(tw, "\tSharedMemory:\t%d MB\n", vm.Summary.QuickStats.SharedMemory)
-			fmt.Fprintf(tw, "\tPrivateMemory:\t%d MB\n", vm.Summary.QuickStats.PrivateMemory)
-			fmt.Fprintf(tw, "\tUptimeSeconds:\t%d s\n", vm.Summary.QuickStats.UptimeSeconds)
-			fmt.Fprintf(tw, "\n")
-			fmt.Fprintf(tw, "Annotations\n")
-			for _, field := range strings.Fields(vm.Summary.Config.Annotation) {
-				f := strings.SplitN(field, ":", 2)
-				fmt.Fprintf(tw, "\t%s:\t%s\n", f[0], f[1])
-			}
-			//fmt.Fprintf(tw, "\n")
-			//fmt.Fprintf(tw, "ExtraConfig\n")
-			//for _, v := range vm.Config.ExtraConfig {
-			//	fmt.Fprintf(tw, "\t%s:\t%s\n", v.GetOptionValue().Key, v.GetOptionValue().Value)
-			//}
-			fmt.Fprintf(tw, "\n")
-			fmt.Fprintf(tw, "Console\n")
-			fmt.Fprintf(tw, "You have 60 seconds to open the URL, or the session will be terminated.\n")
-			fmt.Fprintf(tw, "\t%s\n", console)
+	}
+	if len(refs) == 0 {
+		return nil, newError(ErrNotFound, nil, "No VM found matching: %s", strings.Join(showvm.search, ", "))
+	}
+	if showvm.limit > 0 && len(refs) > showvm.limit {
+		return nil, newError(ErrTooMany, nil, "%d VMs found matching: %s, more than the limit of %d, refine the search or raise the limit", len(refs), strings.Join(showvm.search, ", "), showvm.limit)
+	}
+	log.Debugf("Collecting details of %d VM(s) ...", len(refs))
+	if err := pc.Retrieve(ctx, refs, p, &vms); err != nil {
+		return nil, wrapError(err, "Error retrieving resources information from references")
+	}
+	list := VMDetailList{}
+	for i := range vms {
+		d, err := showvm.detail(ctx, &vms[i])
+		if err != nil {
+			return nil, err
 		}
-		tw.Flush()
+		list = append(list, d)
+	}
+	return list, nil
+}
+
+// Print dumps the details of the VMs. If the console is enabled, it keeps
+// the session open for the console URLs during the configured wait.
+func (showvm *ShowVM) Print(p ...string) error {
+	result, err := showvm.Collect(showvm.ctx, p...)
+	if err := showvm.print(result, err); err != nil {
+		return err
+	}
+	if !hasConsoles(result) {
+		return nil
+	}
+	// Keep stdout clean for the structured formats
+	out := os.Stdout
+	if _, ok := showvm.formatter.(tableFormatter); !ok {
+		out = os.Stderr
 	}
-	fmt.Println()
-	fmt.Println("Waiting for 60 seconds, then exit")
-	time.Sleep(time.Second * 60)
+	showvm.waitConsole(out)
+	return nil
 }
@@ -0,0 +1,248 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+func TestShowVM(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	showvm, err := NewShowVM(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer showvm.Close()
+	list := collect(t, showvm, "dc0_h0_vm0").(VMDetailList)
+	if len(list) != 1 {
+		t.Fatalf("Expected one VM, got %d", len(list))
+	}
+	vm := list[0]
+	if vm.Name != "DC0_H0_VM0" || vm.Runtime.Host == "" || len(vm.Storage.Datastores) == 0 {
+		t.Errorf("Unexpected VM details: %+v", vm)
+	}
+	if len(vm.Devices.Disks) == 0 || vm.Devices.Disks[0].Capacity == 0 || vm.Devices.Disks[0].Bus == "" {
+		t.Errorf("Unexpected disks: %+v", vm.Devices.Disks)
+	}
+	if len(vm.Devices.NICs) == 0 || vm.Devices.NICs[0].Network == "" {
+		t.Errorf("Unexpected NICs: %+v", vm.Devices.NICs)
+	}
+	if vm.Console != "" {
+		t.Errorf("Console URL without -console: %s", vm.Console)
+	}
+	out := render(t, OutputTable, list)
+	for _, section := range []string{"VM config", "Guest", "Runtime env", "Storage", "Devices", "QuickStats"} {
+		if !strings.Contains(out, section) {
+			t.Errorf("Section %s missing in table output:\n%s", section, out)
+		}
+	}
+	var buf bytes.Buffer
+	if err := (csvFormatter{}).Format(&buf, list); err == nil {
+		t.Error("CSV output should not be supported by show")
+	}
+}
+
+func TestLookupKind(t *testing.T) {
+	for s, expected := range map[string][3]string{
+		"10.100.15.10":                         {"ip", "10.100.15.10", "false"},
+		"fe80::1":                              {"ip", "fe80::1", "false"},
+		"ip:10.0.0.1":                          {"ip", "10.0.0.1", "true"},
+		"42254a47-db0a-34f6-be21-d888ca3e0261": {"uuid", "42254a47-db0a-34f6-be21-d888ca3e0261", "false"},
+		"UUID:abc":                             {"uuid", "abc", "true"},
+		"/DC0/vm/web":                          {"path", "/DC0/vm/web", "false"},
+		"path:folder/web":                      {"path", "folder/web", "true"},
+		"web.example.com":                      {"dns", "web.example.com", "false"},
+		"dns:web":                              {"dns", "web", "true"},
+		"DC0_H0_VM0":                           {"", "DC0_H0_VM0", "false"},
+		"vm-42":                                {"", "vm-42", "false"},
+	} {
+		kind, value, forced := lookupKind(s)
+		if kind != expected[0] || value != expected[1] || strconv.FormatBool(forced) != expected[2] {
+			t.Errorf("Unexpected lookup for %s: %s %s %t", s, kind, value, forced)
+		}
+	}
+}
+
+func TestShowVMLookup(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	showvm, err := NewShowVM(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer showvm.Close()
+	vm := collect(t, showvm, "dc0_h0_vm0").(VMDetailList)[0]
+	for _, s := range []string{"uuid:" + vm.Config.UUID, "path:/DC0/vm/DC0_H0_VM0", "path:DC0_H0_VM0"} {
+		showvm, err := NewShowVM(c, "DC0", context.Background())
+		if err != nil {
+			t.Fatalf("Error connecting: %s", err)
+		}
+		defer showvm.Close()
+		list := collect(t, showvm, s).(VMDetailList)
+		if len(list) != 1 || list[0].Reference != vm.Reference {
+			t.Errorf("Unexpected VMs for %s: %v", s, list)
+		}
+	}
+	if _, err := showvm.Search("uuid:00000000-0000-0000-0000-000000000000"); KindOf(err) != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestShowVMSharedIP(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	listvms, err := NewListVMs(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer listvms.Close()
+	list := collect(t, listvms, "*").(VMList)
+	if len(list) < 3 {
+		t.Fatalf("Not enough VMs in the simulator: %v", list)
+	}
+	// Two VMs with the same IP, e.g. in different networks
+	for _, v := range list[:2] {
+		ref := types.ManagedObjectReference{Type: "VirtualMachine", Value: v.Reference}
+		vm := simulator.Map.Get(ref).(*simulator.VirtualMachine)
+		if vm.Guest == nil {
+			vm.Guest = &types.GuestInfo{}
+		}
+		vm.Guest.IpAddress = "10.0.0.42"
+	}
+	showvm, err := NewShowVM(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer showvm.Close()
+	vms := collect(t, showvm, "10.0.0.42").(VMDetailList)
+	found := map[string]bool{}
+	for _, vm := range vms {
+		found[vm.Reference] = true
+	}
+	if len(vms) != 2 || !found[list[0].Reference] || !found[list[1].Reference] {
+		t.Errorf("Expected the VMs %s and %s, got: %v", list[0].Reference, list[1].Reference, found)
+	}
+}
+
+func TestNewMatcher(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		regex   bool
+		value   string
+		match   bool
+	}{
+		{"DC0_H0_VM0", false, "dc0_h0_vm0", true},
+		{"web-*", false, "WEB-01", true},
+		{"web-?", false, "web-10", false},
+		{"10.0.0.*", false, "10.0.0.5", true},
+		{"^web-[0-9]+$", true, "Web-42", true},
+		{"^web-[0-9]+$", true, "web-a", false},
+		{"db", true, "prod-db-01", true},
+	} {
+		m, err := newMatcher(c.pattern, c.regex)
+		if err != nil {
+			t.Errorf("Error with pattern %s: %s", c.pattern, err)
+			continue
+		}
+		if m(c.value) != c.match {
+			t.Errorf("Pattern %s (regex %t) with %s should return %t", c.pattern, c.regex, c.value, c.match)
+		}
+	}
+	if _, err := newMatcher("web-[", false); err == nil {
+		t.Error("Invalid glob accepted")
+	}
+	if _, err := newMatcher("web-(", true); err == nil {
+		t.Error("Invalid regular expression accepted")
+	}
+}
+
+func TestShowVMPatterns(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	for _, tc := range []struct {
+		pattern string
+		regex   bool
+	}{
+		{"Dc0_H0_*", false},
+		{"^dc0_h0_vm[0-9]$", true},
+	} {
+		showvm, err := NewShowVM(c, "DC0", context.Background())
+		if err != nil {
+			t.Fatalf("Error connecting: %s", err)
+		}
+		defer showvm.Close()
+		showvm.SetRegex(tc.regex)
+		list := collect(t, showvm, tc.pattern).(VMDetailList)
+		if len(list) < 2 {
+			t.Errorf("Expected several VMs for %s, got %d", tc.pattern, len(list))
+		}
+		for _, vm := range list {
+			if !strings.HasPrefix(vm.Name, "DC0_H0_VM") {
+				t.Errorf("Unexpected VM for %s: %s", tc.pattern, vm.Name)
+			}
+		}
+	}
+	showvm, err := NewShowVM(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer showvm.Close()
+	showvm.SetLimit(1)
+	if _, err := showvm.Search("*"); err != nil {
+		t.Fatalf("Error searching: %s", err)
+	}
+	if _, err := showvm.Collect(context.Background()); KindOf(err) != ErrTooMany || !strings.Contains(err.Error(), "VMs found") {
+		t.Errorf("Expected an error about the limit, got: %v", err)
+	}
+}
+
+func TestShowVMNotFound(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	showvm, err := NewShowVM(c, "DC0", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer showvm.Close()
+	if _, err := showvm.Search("nonexistent"); err != nil {
+		t.Fatalf("Error searching: %s", err)
+	}
+	_, err = showvm.Collect(context.Background())
+	if KindOf(err) != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+}
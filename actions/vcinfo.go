@@ -15,19 +15,56 @@ package actions
 
 import (
 	"fmt"
-	"net/url"
-	"os"
+	"io"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/go-playground/log"
 	"github.com/vmware/govmomi/find"
-	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	"golang.org/x/net/context"
 )
 
+// AboutInfo describes the VCenter service
+type AboutInfo struct {
+	Name       string `json:"name" yaml:"name"`
+	Vendor     string `json:"vendor" yaml:"vendor"`
+	Version    string `json:"version" yaml:"version"`
+	Build      string `json:"build" yaml:"build"`
+	OsType     string `json:"os_type" yaml:"os_type"`
+	APIType    string `json:"api_type" yaml:"api_type"`
+	APIVersion string `json:"api_version" yaml:"api_version"`
+	ProductID  string `json:"product_id" yaml:"product_id"`
+	UUID       string `json:"uuid" yaml:"uuid"`
+}
+
+// DatacenterInfo is a datacenter available in VCenter
+type DatacenterInfo struct {
+	Reference string `json:"reference" yaml:"reference"`
+	Name      string `json:"name" yaml:"name"`
+}
+
+// VCInfoResult is the data collected by the info action
+type VCInfoResult struct {
+	About       AboutInfo        `json:"about" yaml:"about"`
+	Datacenters []DatacenterInfo `json:"datacenters" yaml:"datacenters"`
+}
+
+// Header returns the columns of the datacenters table
+func (r *VCInfoResult) Header() []string {
+	return []string{"Reference", "Name"}
+}
+
+// Rows returns one record per datacenter
+func (r *VCInfoResult) Rows() [][]string {
+	rows := [][]string{}
+	for _, dc := range r.Datacenters {
+		rows = append(rows, []string{dc.Reference, dc.Name})
+	}
+	return rows
+}
+
 // VCInfo represents a class to gather basic info from Vmware Vcenter
 type VCInfo struct {
 	*base
@@ -36,17 +73,21 @@ type VCInfo struct {
 }
 
 // NewVCInfo constructor
-func NewVCInfo(u *url.URL, insecure bool, dc string, ctx context.Context) *VCInfo {
+func NewVCInfo(c *Connection, dc string, ctx context.Context) (*VCInfo, error) {
 	vc := VCInfo{}
-	vc.base = newBase(u, insecure, dc, ctx)
+	b, err := newBase(c, dc, ctx)
+	if err != nil {
+		return nil, err
+	}
+	vc.base = b
 	log.Debug("VCInfo constructor")
-	return &vc
+	return &vc, nil
 }
 
 // Search gets information from VCenter: available clusters.
 // The parameter accepted represents the base path to perform the search.
 // It will return the number of references found.
-func (vc *VCInfo) Search(s ...string) int {
+func (vc *VCInfo) Search(s ...string) (int, error) {
 
 	if len(s) == 0 {
 		s = []string{"*"}
@@ -55,55 +96,90 @@ func (vc *VCInfo) Search(s ...string) int {
 	vc.about = &vc.client.ServiceContent.About
 	counter := 0
 	finder := find.NewFinder(vc.client.Client, true)
-	if datacenters, err := finder.DatacenterList(vc.ctx, s[0]); err == nil {
+	if datacenters, err := finder.DatacenterList(vc.ctx, s[0]); err != nil {
+		if _, ok := err.(*find.NotFoundError); !ok {
+			return 0, wrapError(err, "Error getting datacenters references")
+		}
+	} else {
 		log.Debugf("Getting list of datacenters")
 		for _, dc := range datacenters {
 			vc.refs = append(vc.refs, dc.Reference())
 			counter++
 		}
-	} else {
-		log.Panicf("Error getting datacenters references: %s", err)
 	}
-	return counter
+	return counter, nil
 }
 
-// Print dumps basic VCenter info and the list of datacenters
-func (vc *VCInfo) Print(p ...string) {
-	var dcs []mo.Datacenter
-
-	if len(p) == 0 {
-		p = []string{"name"}
-	}
-	log.Debug("Printing information ...")
-	tw := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+// WriteTable renders the VCenter info and the list of datacenters
+func (r *VCInfoResult) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 2, ' ', 0)
 	fmt.Fprintf(tw, "\n")
 	fmt.Fprintf(tw, "About\n")
 	fmt.Fprintf(tw, "-----\n")
-	fmt.Fprintf(tw, "Name:\t%s\n", vc.about.Name)
-	fmt.Fprintf(tw, "Vendor:\t%s\n", vc.about.Vendor)
-	fmt.Fprintf(tw, "Version:\t%s\n", vc.about.Version)
-	fmt.Fprintf(tw, "Build:\t%s\n", vc.about.Build)
-	fmt.Fprintf(tw, "OS type:\t%s\n", vc.about.OsType)
-	fmt.Fprintf(tw, "API type:\t%s\n", vc.about.ApiType)
-	fmt.Fprintf(tw, "API version:\t%s\n", vc.about.ApiVersion)
-	fmt.Fprintf(tw, "Product ID:\t%s\n", vc.about.ProductLineId)
-	fmt.Fprintf(tw, "UUID:\t%s\n", vc.about.InstanceUuid)
-	if pc, err := property.DefaultCollector(vc.client.Client).Create(vc.ctx); err == nil {
+	fmt.Fprintf(tw, "Name:\t%s\n", r.About.Name)
+	fmt.Fprintf(tw, "Vendor:\t%s\n", r.About.Vendor)
+	fmt.Fprintf(tw, "Version:\t%s\n", r.About.Version)
+	fmt.Fprintf(tw, "Build:\t%s\n", r.About.Build)
+	fmt.Fprintf(tw, "OS type:\t%s\n", r.About.OsType)
+	fmt.Fprintf(tw, "API type:\t%s\n", r.About.APIType)
+	fmt.Fprintf(tw, "API version:\t%s\n", r.About.APIVersion)
+	fmt.Fprintf(tw, "Product ID:\t%s\n", r.About.ProductID)
+	fmt.Fprintf(tw, "UUID:\t%s\n", r.About.UUID)
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "Datacenters\n")
+	fmt.Fprintf(tw, "-----------\n")
+	for _, dc := range r.Datacenters {
+		fmt.Fprintf(tw, "%s\t", dc.Reference)
+		fmt.Fprintf(tw, "%s\t", dc.Name)
 		fmt.Fprintf(tw, "\n")
-		fmt.Fprintf(tw, "Datacenters\n")
-		fmt.Fprintf(tw, "-----------\n")
-		if err := pc.Retrieve(vc.ctx, vc.refs, p, &dcs); err != nil {
-			log.Errorf("Error retrieving datacenter properties: %s", err)
-		} else {
-			for _, dc := range dcs {
-				fmt.Fprintf(tw, "%s\t", dc.Reference())
-				fmt.Fprintf(tw, "%s\t", dc.Name)
-				fmt.Fprintf(tw, "\n")
-			}
-		}
-	} else {
-		log.Errorf("Error creating collector: %s", err)
 	}
 	fmt.Fprintf(tw, "\n")
-	tw.Flush()
+	return tw.Flush()
+}
+
+// Collect gets the basic VCenter info and the properties of the
+// datacenters found by Search. It returns a *VCInfoResult.
+func (vc *VCInfo) Collect(ctx context.Context, p ...string) (Result, error) {
+	var dcs []mo.Datacenter
+
+	if len(p) == 0 {
+		p = []string{"name"}
+	}
+	log.Debug("Collecting information ...")
+	r := VCInfoResult{
+		About: AboutInfo{
+			Name:       vc.about.Name,
+			Vendor:     vc.about.Vendor,
+			Version:    vc.about.Version,
+			Build:      vc.about.Build,
+			OsType:     vc.about.OsType,
+			APIType:    vc.about.ApiType,
+			APIVersion: vc.about.ApiVersion,
+			ProductID:  vc.about.ProductLineId,
+			UUID:       vc.about.InstanceUuid,
+		},
+		Datacenters: []DatacenterInfo{},
+	}
+	if len(vc.refs) == 0 {
+		return &r, nil
+	}
+	pc, err := vc.newCollector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := pc.Retrieve(ctx, vc.refs, p, &dcs); err != nil {
+		return nil, wrapError(err, "Error retrieving datacenter properties")
+	}
+	for _, dc := range dcs {
+		r.Datacenters = append(r.Datacenters, DatacenterInfo{
+			Reference: dc.Reference().String(),
+			Name:      dc.Name,
+		})
+	}
+	return &r, nil
+}
+
+// Print dumps basic VCenter info and the list of datacenters
+func (vc *VCInfo) Print(p ...string) error {
+	return vc.print(vc.Collect(vc.ctx, p...))
 }
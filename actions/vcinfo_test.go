@@ -0,0 +1,74 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestVCInfo(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	vc, err := NewVCInfo(c, "", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer vc.Close()
+	r := collect(t, vc, "*").(*VCInfoResult)
+	if r.About.APIType != "VirtualCenter" {
+		t.Errorf("Unexpected API type: %s", r.About.APIType)
+	}
+	if len(r.Datacenters) != 1 || r.Datacenters[0].Name != "DC0" {
+		t.Errorf("Unexpected datacenters: %v", r.Datacenters)
+	}
+	if out := render(t, OutputTable, r); !strings.Contains(out, "DC0") {
+		t.Errorf("Datacenter missing in table output:\n%s", out)
+	}
+	if out := render(t, OutputCSV, r); !strings.HasPrefix(out, "Reference,Name\n") {
+		t.Errorf("Unexpected CSV output:\n%s", out)
+	}
+}
+
+func TestVCInfoNoDatacenter(t *testing.T) {
+	u, stop := vcsim(t)
+	defer stop()
+	c := connect(t, u, ConnectOptions{})
+	defer c.Close()
+
+	vc, err := NewVCInfo(c, "", context.Background())
+	if err != nil {
+		t.Fatalf("Error connecting: %s", err)
+	}
+	defer vc.Close()
+	if n, err := vc.Search("nonexistent*"); err != nil || n != 0 {
+		t.Fatalf("Expected no datacenters, got %d: %v", n, err)
+	}
+	r, err := vc.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Error collecting: %s", err)
+	}
+	info := r.(*VCInfoResult)
+	if info.About.APIType != "VirtualCenter" || len(info.Datacenters) != 0 {
+		t.Errorf("Unexpected result: %+v", info)
+	}
+	if out := render(t, OutputTable, r); !strings.Contains(out, "About") {
+		t.Errorf("About block missing in table output:\n%s", out)
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// VMController is a storage controller (SCSI, SATA or IDE) of a VM
+type VMController struct {
+	Label   string `json:"label" yaml:"label"`
+	Type    string `json:"type" yaml:"type"`
+	Bus     int32  `json:"bus" yaml:"bus"`
+	Devices int    `json:"devices" yaml:"devices"`
+}
+
+// VMDisk is a virtual disk of a VM. Capacity is in bytes, Provisioning
+// is thin, thick, eager, sparse or rdm and Bus is like scsi0:1
+type VMDisk struct {
+	Label        string `json:"label" yaml:"label"`
+	Capacity     int64  `json:"capacity" yaml:"capacity"`
+	File         string `json:"file" yaml:"file"`
+	Datastore    string `json:"datastore" yaml:"datastore"`
+	Provisioning string `json:"provisioning" yaml:"provisioning"`
+	Controller   string `json:"controller" yaml:"controller"`
+	Bus          string `json:"bus" yaml:"bus"`
+}
+
+// VMNic is a virtual network adapter of a VM
+type VMNic struct {
+	Label      string `json:"label" yaml:"label"`
+	MacAddress string `json:"mac_address" yaml:"mac_address"`
+	Type       string `json:"type" yaml:"type"`
+	Connected  bool   `json:"connected" yaml:"connected"`
+	Network    string `json:"network" yaml:"network"`
+}
+
+// VMDevices is the virtual hardware of a VM
+type VMDevices struct {
+	Controllers []VMController `json:"controllers" yaml:"controllers"`
+	Disks       []VMDisk       `json:"disks" yaml:"disks"`
+	NICs        []VMNic        `json:"nics" yaml:"nics"`
+}
+
+// deviceType returns the short name of the device type,
+// e.g. vmxnet3 for *types.VirtualVmxnet3
+func deviceType(d types.BaseVirtualDevice) string {
+	t := strings.TrimPrefix(fmt.Sprintf("%T", d), "*types.")
+	t = strings.TrimPrefix(t, "Virtual")
+	t = strings.TrimSuffix(t, "Controller")
+	return strings.ToLower(t)
+}
+
+func deviceLabel(d *types.VirtualDevice) string {
+	if d.DeviceInfo != nil {
+		return d.DeviceInfo.GetDescription().Label
+	}
+	return fmt.Sprintf("Device %d", d.Key)
+}
+
+// controllerBus returns the bus prefix of the storage controllers or ""
+// for the other controllers (PCI, PS2, USB ...)
+func controllerBus(d types.BaseVirtualDevice) string {
+	switch d.(type) {
+	case types.BaseVirtualSCSIController:
+		return "scsi"
+	case types.BaseVirtualSATAController:
+		return "sata"
+	case *types.VirtualIDEController:
+		return "ide"
+	}
+	return ""
+}
+
+func diskProvisioning(backing types.BaseVirtualDeviceBackingInfo) string {
+	switch b := backing.(type) {
+	case *types.VirtualDiskFlatVer2BackingInfo:
+		if b.ThinProvisioned != nil && *b.ThinProvisioned {
+			return "thin"
+		}
+		if b.EagerlyScrub != nil && *b.EagerlyScrub {
+			return "eager"
+		}
+		return "thick"
+	case *types.VirtualDiskSparseVer2BackingInfo:
+		return "sparse"
+	case *types.VirtualDiskRawDiskMappingVer1BackingInfo:
+		return "rdm"
+	}
+	return "-"
+}
+
+// vmDevices gets the controllers, disks and NICs of the VM from
+// config.hardware.device. The datastore and port group names are taken
+// from the maps indexed by reference value.
+func vmDevices(vm *mo.VirtualMachine, datastores map[string]string, networks map[string]string) VMDevices {
+	devs := VMDevices{
+		Controllers: []VMController{},
+		Disks:       []VMDisk{},
+		NICs:        []VMNic{},
+	}
+	if vm.Config == nil {
+		return devs
+	}
+	controllers := make(map[int32]types.BaseVirtualDevice)
+	for _, d := range vm.Config.Hardware.Device {
+		if c, ok := d.(types.BaseVirtualController); ok && controllerBus(d) != "" {
+			controllers[d.GetVirtualDevice().Key] = d
+			vc := c.GetVirtualController()
+			devs.Controllers = append(devs.Controllers, VMController{
+				Label:   deviceLabel(d.GetVirtualDevice()),
+				Type:    deviceType(d),
+				Bus:     vc.BusNumber,
+				Devices: len(vc.Device),
+			})
+		}
+	}
+	for _, d := range vm.Config.Hardware.Device {
+		switch dev := d.(type) {
+		case *types.VirtualDisk:
+			disk := VMDisk{
+				Label:        deviceLabel(&dev.VirtualDevice),
+				Capacity:     dev.CapacityInKB * 1024,
+				Provisioning: diskProvisioning(dev.Backing),
+			}
+			if b, ok := dev.Backing.(types.BaseVirtualDeviceFileBackingInfo); ok {
+				fb := b.GetVirtualDeviceFileBackingInfo()
+				disk.File = fb.FileName
+				if fb.Datastore != nil {
+					disk.Datastore = datastores[fb.Datastore.Value]
+				}
+			}
+			if c, ok := controllers[dev.ControllerKey]; ok {
+				disk.Controller = deviceLabel(c.GetVirtualDevice())
+				bus := c.(types.BaseVirtualController).GetVirtualController().BusNumber
+				disk.Bus = fmt.Sprintf("%s%d", controllerBus(c), bus)
+				if dev.UnitNumber != nil {
+					disk.Bus = fmt.Sprintf("%s:%d", disk.Bus, *dev.UnitNumber)
+				}
+			}
+			devs.Disks = append(devs.Disks, disk)
+		case types.BaseVirtualEthernetCard:
+			card := dev.GetVirtualEthernetCard()
+			nic := VMNic{
+				Label:      deviceLabel(&card.VirtualDevice),
+				MacAddress: card.MacAddress,
+				Type:       deviceType(d),
+			}
+			if card.Connectable != nil {
+				nic.Connected = card.Connectable.Connected
+			}
+			switch b := card.Backing.(type) {
+			case *types.VirtualEthernetCardNetworkBackingInfo:
+				nic.Network = b.DeviceName
+			case *types.VirtualEthernetCardDistributedVirtualPortBackingInfo:
+				nic.Network = networks[b.Port.PortgroupKey]
+				if nic.Network == "" {
+					nic.Network = b.Port.PortgroupKey
+				}
+			}
+			devs.NICs = append(devs.NICs, nic)
+		}
+	}
+	return devs
+}
+
+func (devs *VMDevices) writeTable(tw io.Writer) {
+	fmt.Fprintf(tw, "Devices\n")
+	if len(devs.Controllers) > 0 {
+		fmt.Fprintf(tw, "\tControllers:\n")
+		for _, c := range devs.Controllers {
+			fmt.Fprintf(tw, "\t\t%s:\t%s bus %d, %d device(s)\n", c.Label, c.Type, c.Bus, c.Devices)
+		}
+	}
+	if len(devs.Disks) > 0 {
+		fmt.Fprintf(tw, "\tDisks:\n")
+		for _, d := range devs.Disks {
+			fmt.Fprintf(tw, "\t\t%s:\t%s %s on %s, %s (%s)\n", d.Label, units.ByteSize(d.Capacity), d.Provisioning, d.Bus, d.File, d.Datastore)
+		}
+	}
+	if len(devs.NICs) > 0 {
+		fmt.Fprintf(tw, "\tNICs:\n")
+		for _, n := range devs.NICs {
+			state := "disconnected"
+			if n.Connected {
+				state = "connected"
+			}
+			fmt.Fprintf(tw, "\t\t%s:\t%s %s %s on %s\n", n.Label, n.MacAddress, n.Type, state, n.Network)
+		}
+	}
+}
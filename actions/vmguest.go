@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+// VMGuestNic is a network interface reported by VMware Tools. The
+// addresses include the prefix length when the guest reports it.
+type VMGuestNic struct {
+	MacAddress string   `json:"mac_address" yaml:"mac_address"`
+	Network    string   `json:"network" yaml:"network"`
+	Connected  bool     `json:"connected" yaml:"connected"`
+	Addresses  []string `json:"addresses" yaml:"addresses"`
+}
+
+// VMGuestRoute is an entry of the guest routing table
+type VMGuestRoute struct {
+	Network string `json:"network" yaml:"network"`
+	Gateway string `json:"gateway" yaml:"gateway"`
+	Device  string `json:"device" yaml:"device"`
+}
+
+// VMGuestDNS is the DNS configuration of the guest
+type VMGuestDNS struct {
+	DHCP       bool     `json:"dhcp" yaml:"dhcp"`
+	HostName   string   `json:"hostname" yaml:"hostname"`
+	DomainName string   `json:"domain_name" yaml:"domain_name"`
+	Servers    []string `json:"servers" yaml:"servers"`
+	Search     []string `json:"search" yaml:"search"`
+}
+
+// guestNetwork gets the NICs from guest.net and the routes and DNS
+// configuration from guest.ipStack, when VMware Tools reports them
+func guestNetwork(vm *mo.VirtualMachine) ([]VMGuestNic, []VMGuestRoute, *VMGuestDNS) {
+	nics := []VMGuestNic{}
+	routes := []VMGuestRoute{}
+	var dns *VMGuestDNS
+
+	if vm.Guest == nil {
+		return nics, routes, dns
+	}
+	for _, n := range vm.Guest.Net {
+		nic := VMGuestNic{
+			MacAddress: n.MacAddress,
+			Network:    n.Network,
+			Connected:  n.Connected,
+			Addresses:  []string{},
+		}
+		if n.IpConfig != nil {
+			for _, ip := range n.IpConfig.IpAddress {
+				nic.Addresses = append(nic.Addresses, fmt.Sprintf("%s/%d", ip.IpAddress, ip.PrefixLength))
+			}
+		} else {
+			nic.Addresses = append(nic.Addresses, n.IpAddress...)
+		}
+		nics = append(nics, nic)
+	}
+	for _, stack := range vm.Guest.IpStack {
+		if stack.IpRouteConfig != nil {
+			for _, r := range stack.IpRouteConfig.IpRoute {
+				routes = append(routes, VMGuestRoute{
+					Network: fmt.Sprintf("%s/%d", r.Network, r.PrefixLength),
+					Gateway: r.Gateway.IpAddress,
+					Device:  r.Gateway.Device,
+				})
+			}
+		}
+		if stack.DnsConfig != nil && dns == nil {
+			c := stack.DnsConfig.GetNetDnsConfigInfo()
+			dns = &VMGuestDNS{
+				DHCP:       c.Dhcp,
+				HostName:   c.HostName,
+				DomainName: c.DomainName,
+				Servers:    c.IpAddress,
+				Search:     c.SearchDomain,
+			}
+		}
+	}
+	return nics, routes, dns
+}
+
+func (g *VMGuest) writeNetwork(tw io.Writer) {
+	if len(g.Nics) > 0 {
+		fmt.Fprintf(tw, "\tNICs:\n")
+		for _, n := range g.Nics {
+			state := "disconnected"
+			if n.Connected {
+				state = "connected"
+			}
+			fmt.Fprintf(tw, "\t\t%s:\t%s %s %s\n", n.MacAddress, n.Network, state, strings.Join(n.Addresses, ", "))
+		}
+	}
+	if len(g.Routes) > 0 {
+		fmt.Fprintf(tw, "\tRoutes:\n")
+		for _, r := range g.Routes {
+			gateway := r.Gateway
+			if gateway == "" {
+				gateway = "-"
+			}
+			fmt.Fprintf(tw, "\t\t%s:\tvia %s dev %s\n", r.Network, gateway, r.Device)
+		}
+	}
+	if g.DNS != nil {
+		fmt.Fprintf(tw, "\tDNS:\n")
+		fmt.Fprintf(tw, "\t\tDHCP:\t%t\n", g.DNS.DHCP)
+		fmt.Fprintf(tw, "\t\tHostName:\t%s\n", g.DNS.HostName)
+		fmt.Fprintf(tw, "\t\tDomainName:\t%s\n", g.DNS.DomainName)
+		fmt.Fprintf(tw, "\t\tServers:\t%s\n", strings.Join(g.DNS.Servers, ", "))
+		fmt.Fprintf(tw, "\t\tSearch:\t%s\n", strings.Join(g.DNS.Search, ", "))
+	}
+}
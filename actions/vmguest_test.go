@@ -0,0 +1,83 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestGuestNetwork(t *testing.T) {
+	// The simulator does not report guest networking, build it here
+	vm := mo.VirtualMachine{
+		Guest: &types.GuestInfo{
+			Net: []types.GuestNicInfo{
+				{
+					Network:    "VM Network",
+					MacAddress: "00:50:56:00:00:01",
+					Connected:  true,
+					IpConfig: &types.NetIpConfigInfo{
+						IpAddress: []types.NetIpConfigInfoIpAddress{
+							{IpAddress: "10.0.0.5", PrefixLength: 24},
+							{IpAddress: "fe80::1", PrefixLength: 64},
+						},
+					},
+				},
+				{Network: "Backup", MacAddress: "00:50:56:00:00:02", IpAddress: []string{"192.168.1.5"}},
+			},
+			IpStack: []types.GuestStackInfo{
+				{
+					DnsConfig: &types.NetDnsConfigInfo{
+						HostName:     "vm0",
+						DomainName:   "example.com",
+						IpAddress:    []string{"10.0.0.2"},
+						SearchDomain: []string{"example.com"},
+					},
+					IpRouteConfig: &types.NetIpRouteConfigInfo{
+						IpRoute: []types.NetIpRouteConfigInfoIpRoute{
+							{
+								Network:      "0.0.0.0",
+								PrefixLength: 0,
+								Gateway:      types.NetIpRouteConfigInfoGateway{IpAddress: "10.0.0.1", Device: "0"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	nics, routes, dns := guestNetwork(&vm)
+	if len(nics) != 2 || strings.Join(nics[0].Addresses, " ") != "10.0.0.5/24 fe80::1/64" {
+		t.Errorf("Unexpected guest NICs: %+v", nics)
+	}
+	if nics[1].Connected || len(nics[1].Addresses) != 1 || nics[1].Addresses[0] != "192.168.1.5" {
+		t.Errorf("Unexpected guest NIC without IP config: %+v", nics[1])
+	}
+	if len(routes) != 1 || routes[0].Network != "0.0.0.0/0" || routes[0].Gateway != "10.0.0.1" {
+		t.Errorf("Unexpected guest routes: %+v", routes)
+	}
+	if dns == nil || dns.DomainName != "example.com" || len(dns.Servers) != 1 {
+		t.Errorf("Unexpected guest DNS: %+v", dns)
+	}
+	d := VMDetail{Guest: VMGuest{Nics: nics, Routes: routes, DNS: dns}}
+	out := render(t, OutputTable, &d)
+	for _, s := range []string{"10.0.0.5/24", "via 10.0.0.1", "example.com"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%s missing in table output:\n%s", s, out)
+		}
+	}
+}
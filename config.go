@@ -0,0 +1,212 @@
+/*
+Copyright (c) 2016 Jose Riguera Lopez. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Profile are the connection settings of a VCenter in the config file.
+// The password is not stored, use WMINFO_PASSWORD or the URL.
+type Profile struct {
+	URL         string `yaml:"url"`
+	Username    string `yaml:"username"`
+	Datacenter  string `yaml:"datacenter"`
+	Insecure    bool   `yaml:"insecure"`
+	CABundle    string `yaml:"ca_bundle"`
+	ConsolePort int    `yaml:"console_port"`
+}
+
+// Config is the content of the config file, e.g.:
+//
+//	default_profile: prod-ams
+//	profiles:
+//	  prod-ams:
+//	    url: https://vcenter-ams.example.com/sdk
+//	    username: readonly@vsphere.local
+//	    datacenter: Amsterdam
+//	    ca_bundle: ~/.config/wminfo/ams-ca.pem
+type Config struct {
+	DefaultProfile string             `yaml:"default_profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// DefaultConfigFile returns the path of the config file in the user's
+// config directory, $XDG_CONFIG_HOME or ~/.config
+func DefaultConfigFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "wminfo", "config.yaml")
+}
+
+// DefaultSessionDir returns the directory of the session cache in the
+// user's cache directory, $XDG_CACHE_HOME or ~/.cache
+func DefaultSessionDir() string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".cache")
+	}
+	return filepath.Join(dir, "wminfo", "sessions")
+}
+
+// expandHome replaces a leading ~ with the home directory of the user
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return filepath.Join(os.Getenv("HOME"), path[1:])
+	}
+	return path
+}
+
+// LoadConfig reads the config file. A missing file is an empty config.
+func LoadConfig(file string) (*Config, error) {
+	config := Config{}
+	data, err := ioutil.ReadFile(expandHome(file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &config, nil
+		}
+		return nil, fmt.Errorf("Error reading config file: %s", err)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("Error parsing config file %s: %s", file, err)
+	}
+	return &config, nil
+}
+
+// Profile returns the profile with the name, or the default profile if
+// the name is empty. It returns nil if there is no profile to use.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		names := []string{}
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("Profile '%s' not found in the config file, available profiles: %s", name, strings.Join(names, ", "))
+	}
+	return &p, nil
+}
+
+// Endpoint are the connection settings of a vCenter, from the flags,
+// the environment and the profile
+type Endpoint struct {
+	Name        string
+	URL         string
+	DC          string
+	Insecure    bool
+	CABundle    string
+	ConsolePort int
+}
+
+// Apply returns the endpoint with the settings of the profile which were
+// not given in the command line (explicit has the names of the flags
+// given) nor with their environment variable (envs maps the flag names
+// to their variables), so the precedence is flag > env > profile. The
+// username of the profile is only used with its URL.
+func (p *Profile) Apply(e Endpoint, explicit map[string]bool, envs map[string]string) (Endpoint, error) {
+	unset := func(name string) bool {
+		return !explicit[name] && os.Getenv(envs[name]) == ""
+	}
+	if p.URL != "" && unset("url") {
+		u, err := url.Parse(p.URL)
+		if err != nil {
+			return e, fmt.Errorf("Invalid url in profile: %s", err)
+		}
+		if p.Username != "" && u.User == nil {
+			u.User = url.User(p.Username)
+		}
+		e.URL = u.String()
+	}
+	if p.Datacenter != "" && unset("dc") {
+		e.DC = p.Datacenter
+	}
+	if p.Insecure && unset("insecure") {
+		e.Insecure = true
+	}
+	if p.CABundle != "" && unset("ca-bundle") {
+		e.CABundle = expandHome(p.CABundle)
+	}
+	if p.ConsolePort != 0 && unset("console-port") {
+		e.ConsolePort = p.ConsolePort
+	}
+	return e, nil
+}
+
+// Endpoints returns the vCenters to query. With several profiles there is
+// one endpoint per profile, named like it, and the URL cannot be given by
+// flag nor environment. Otherwise there is one per URL,
+// named by its host, with the settings of the profile (or the default
+// profile). The defaults are the settings given by flags or environment.
+func (c *Config) Endpoints(defaults Endpoint, urls, profiles []string, explicit map[string]bool, envs map[string]string) ([]Endpoint, error) {
+	endpoints := []Endpoint{}
+	if len(profiles) > 1 {
+		if explicit["url"] || os.Getenv(envs["url"]) != "" {
+			return nil, fmt.Errorf("-url and %s cannot be used with several profiles", envs["url"])
+		}
+		for _, name := range profiles {
+			p, err := c.Profile(name)
+			if err != nil {
+				return nil, err
+			}
+			e, err := p.Apply(defaults, explicit, envs)
+			if err != nil {
+				return nil, fmt.Errorf("Profile '%s': %s", name, err)
+			}
+			e.Name = name
+			endpoints = append(endpoints, e)
+		}
+		return endpoints, nil
+	}
+	name := ""
+	if len(profiles) == 1 {
+		name = profiles[0]
+	}
+	p, err := c.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range urls {
+		e := defaults
+		e.URL = u
+		if p != nil {
+			if e, err = p.Apply(e, explicit, envs); err != nil {
+				return nil, err
+			}
+		}
+		parsed, err := url.Parse(e.URL)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing URL: %s", err)
+		}
+		e.Name = parsed.Host
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, nil
+}